@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"github.com/hi-unc1e/s3viewer-go/s3viewer"
+	"io"
+	"os"
+)
+
+type batchOptions struct {
+	list     string
+	endpoint string
+	workers  int
+	hostRate float64
+	maxPage  int
+	outDir   string
+	format   string
+	summary  string
+	classify bool
+	rules    string
+	filters  *filterFlags
+}
+
+// runBatch 实现 `-l targets.txt`：并发爬取多个目标，打印汇总；单个目标失败只记录在汇总里
+func runBatch(ctx context.Context, opts batchOptions) {
+	var input io.Reader = os.Stdin
+	if opts.list != "-" {
+		file, err := os.Open(opts.list)
+		if err != nil {
+			fatalf("Failed to open target list: %v", err)
+		}
+		defer file.Close()
+		input = file
+	}
+	targets, err := s3viewer.ReadTargets(input)
+	if err != nil {
+		fatalf("Failed to read target list: %v", err)
+	}
+	for i, target := range targets {
+		if normalized, err := s3viewer.NormalizeURL(target, opts.endpoint); err == nil {
+			targets[i] = normalized
+		}
+	}
+	if len(targets) == 0 {
+		fatalf("No targets in %v", opts.list)
+	}
+
+	filter, err := opts.filters.Filter()
+	if err != nil {
+		fatalf("Invalid filter: %v", err)
+	}
+	batch := &s3viewer.Batch{
+		Workers:  opts.workers,
+		HostRate: opts.hostRate,
+		MaxPage:  opts.maxPage,
+		OutDir:   opts.outDir,
+		Format:   opts.format,
+		Filter:   filter,
+	}
+	if opts.classify || opts.rules != "" {
+		if batch.Classifier, err = loadClassifier(opts.rules); err != nil {
+			fatalf("%v", err)
+		}
+	}
+
+	infof("Targets: %v", len(targets))
+	results := batch.RunContext(ctx, targets)
+
+	if err := s3viewer.PrintBatchSummary(os.Stdout, results); err != nil {
+		fatalf("Failed to print summary: %v", err)
+	}
+	if opts.summary != "" {
+		if err := s3viewer.SaveBatchSummary(results, opts.summary); err != nil {
+			fatalf("Failed to save summary: %v", err)
+		}
+		infof("Saved summary into %v", opts.summary)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/hi-unc1e/s3viewer-go/s3viewer"
+	"os"
+)
+
+// runDiff 实现 `s3v diff`：比较同一个 bucket 两次导出的结果
+func runDiff(args []string) {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	format := fs.String("format", "table", "output format: table, json or html")
+	output := fs.String("o", "", "write the diff to this file instead of stdout")
+	logging := addLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), s3viewer.T("Usage:")+" s3v diff [-format table|json|html] [-o file] old.(csv|json) new.(csv|json)")
+		fs.PrintDefaults()
+	}
+	parseFlags(fs, args, logging)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	oldPath, newPath := fs.Arg(0), fs.Arg(1)
+	oldResult, err := s3viewer.LoadSnapshot(oldPath)
+	if err != nil {
+		fatalf("Failed to load %v: %v", oldPath, err)
+	}
+	newResult, err := s3viewer.LoadSnapshot(newPath)
+	if err != nil {
+		fatalf("Failed to load %v: %v", newPath, err)
+	}
+
+	d := s3viewer.Diff(oldResult, newResult)
+	if err := writeDiff(d, *format, *output, fmt.Sprintf("%v -> %v", oldPath, newPath)); err != nil {
+		fatalf("Failed to write diff: %v", err)
+	}
+}
+
+// writeDiff 有 output 时写到文件，否则写到终端
+func writeDiff(d *s3viewer.DiffResult, format string, output string, title string) error {
+	if output == "" {
+		return s3viewer.WriteDiff(os.Stdout, d, format, title)
+	}
+	file, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := s3viewer.WriteDiff(file, d, format, title); err != nil {
+		return err
+	}
+	infof("Saved diff into %v", output)
+	return nil
+}
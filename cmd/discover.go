@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"github.com/hi-unc1e/s3viewer-go/s3viewer"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// runDiscover 实现 `s3v discover`：根据公司关键字生成候选 bucket 名并探测是否存在
+func runDiscover(args []string) {
+	fs := flag.NewFlagSet("discover", flag.ExitOnError)
+	var keywords, templates stringList
+	fs.Var(&keywords, "k", "company keyword, can be repeated")
+	fs.Var(&templates, "t", "endpoint template with {name}, can be repeated (default https://{name}.s3.amazonaws.com)")
+	wordlist := fs.String("w", "", "wordlist file for prefixes/suffixes, one word per line (default built-in list)")
+	years := fs.String("years", "", "years appended to names, e.g. 2023,2024 (default the last 3 years)")
+	regions := fs.String("regions", "", "regions appended to names, e.g. cn-hangzhou,ap-east-1")
+	workers := fs.Int("c", 20, "number of concurrent probes")
+	output := fs.String("o", "", "write matched URLs to this file, one per line, usable with -l")
+	withDenied := fs.Bool("denied", false, "also output buckets that exist but deny listing")
+	dryRun := fs.Bool("dry-run", false, "only print the candidate URLs")
+	logging := addLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), s3viewer.T("Usage:")+" s3v discover -k keyword [-t https://{name}.oss-cn-hangzhou.aliyuncs.com] [-w words.txt] [-o found.txt]")
+		fs.PrintDefaults()
+	}
+	parseFlags(fs, args, logging)
+
+	if len(keywords) == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if len(templates) == 0 {
+		templates = stringList{"https://{name}.s3.amazonaws.com"}
+	}
+	for _, t := range templates {
+		if !strings.Contains(t, "{name}") {
+			fatalf("Invalid -t, {name} is missing: %v", t)
+		}
+	}
+
+	generator := &s3viewer.NameGenerator{Keywords: keywords, Years: splitList(*years), Regions: splitList(*regions)}
+	if *years == "" {
+		year := time.Now().Year()
+		for y := year - 2; y <= year; y++ {
+			generator.Years = append(generator.Years, strconv.Itoa(y))
+		}
+	}
+	if *wordlist != "" {
+		words, err := readLines(*wordlist)
+		if err != nil {
+			fatalf("Failed to read wordlist: %v", err)
+		}
+		generator.Words = words
+	}
+	candidates := s3viewer.Candidates(generator.Generate(), templates)
+	infof("Candidates: %v", len(candidates))
+
+	if *dryRun {
+		for _, c := range candidates {
+			fmt.Println(c.Url)
+		}
+		return
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+	prober := &s3viewer.Prober{Workers: *workers}
+	results := prober.ProbeContext(ctx, candidates)
+	if err := s3viewer.PrintProbeResults(os.Stderr, results); err != nil {
+		fatalf("Failed to print results: %v", err)
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			fatalf("Failed to create output file: %v", err)
+		}
+		defer file.Close()
+		out = file
+	}
+	if err := s3viewer.WriteProbeTargets(out, results, *withDenied); err != nil {
+		fatalf("Failed to write results: %v", err)
+	}
+}
+
+// splitList 逗号分隔的参数
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// readLines 按行读取文件，忽略空行和 # 开头的注释
+func readLines(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
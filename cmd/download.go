@@ -0,0 +1,110 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/hi-unc1e/s3viewer-go/s3viewer"
+	"os"
+)
+
+// runDownload 实现 `s3v download`：把 bucket（或之前导出的 CSV/JSON）里的对象镜像到本地目录
+func runDownload(args []string) {
+	fs := flag.NewFlagSet("download", flag.ExitOnError)
+	url := fs.String("u", "", "s3 URL, such as http://bucket.s3.amazonaws.com/, s3://bucket/prefix or oss://bucket")
+	endpoint := fs.String("endpoint", "", "endpoint for s3:// oss:// cos:// URLs or path-style hosts")
+	input := fs.String("i", "", "previously exported .csv or .json file, instead of -u")
+	maxPage := fs.Int("p", 1, "max page")
+	dir := fs.String("d", ".", "local directory to mirror into")
+	workers := fs.Int("c", 4, "number of concurrent downloads")
+	limit := fs.String("limit", "", "total bandwidth limit per second, e.g. 512K, 2MB")
+	noProgress := fs.Bool("no-progress", false, "do not show the progress line")
+	verify := fs.Bool("verify", false, "verify downloads against the ETag (MD5) and quarantine corrupted files")
+	partSize := fs.String("part-size", "", "part size of multipart uploads, e.g. 8MB; inferred when empty")
+	report := fs.String("report", "", "write a CSV report of verification mismatches to this file")
+	quarantine := fs.String("quarantine", "", "directory for corrupted files (default <dir>/.quarantine)")
+	filters := addFilterFlags(fs)
+	logging := addLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), s3viewer.T("Usage:")+" s3v download (-u s3_url [-p max_page] | -i export.csv) [-d dir] [-c workers] [filters]")
+		fs.PrintDefaults()
+	}
+	parseFlags(fs, args, logging)
+
+	if (*url == "") == (*input == "") {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if *url != "" {
+		normalized, err := s3viewer.NormalizeURL(*url, *endpoint)
+		if err != nil {
+			fatalf("Invalid -u: %v", err)
+		}
+		*url = normalized
+	}
+	filter, err := filters.Filter()
+	if err != nil {
+		fatalf("Invalid filter: %v", err)
+	}
+	rateLimit, err := s3viewer.ParseSize(*limit)
+	if err != nil {
+		fatalf("Invalid -limit: %v", err)
+	}
+	multipartSize, err := s3viewer.ParseSize(*partSize)
+	if err != nil {
+		fatalf("Invalid -part-size: %v", err)
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+
+	var result *s3viewer.ListBucketResult
+	if *input != "" {
+		result, err = s3viewer.LoadSnapshot(*input)
+	} else {
+		result, err = s3viewer.LoadRemoteHTTPRecursiveContext(ctx, *url, *maxPage)
+	}
+	// 中断时用已经拉取到的部分继续；一个都没拉到时没法继续
+	if err != nil && (ctx.Err() == nil || result == nil) {
+		fatalf("Failed to load file list: %v", err)
+	}
+	files := filter.Apply(result.Files)
+
+	downloader := &s3viewer.Downloader{
+		Dir:       *dir,
+		Workers:   *workers,
+		RateLimit: rateLimit,
+
+		Verify:        *verify || *report != "",
+		PartSize:      multipartSize,
+		QuarantineDir: *quarantine,
+	}
+	if !*noProgress {
+		downloader.Progress = os.Stderr
+	}
+	infof("Downloading %v objects into %v", len(files), *dir)
+	results := downloader.DownloadContext(ctx, files)
+
+	counts := make(map[string]int)
+	for _, r := range results {
+		counts[r.Status]++
+		// 没有校验的结果 Verify 为空，不计数
+		if r.Verify != "" {
+			counts[r.Verify]++
+		}
+	}
+	infof("Download done: downloaded=%v resumed=%v skipped=%v failed=%v",
+		counts[s3viewer.DownloadOK], counts[s3viewer.DownloadResumed], counts[s3viewer.DownloadSkipped], counts[s3viewer.DownloadFailed])
+	if downloader.Verify {
+		infof("Verify: ok=%v mismatch=%v unverified=%v",
+			counts[s3viewer.VerifyOK], counts[s3viewer.VerifyMismatch], counts[s3viewer.VerifyUnverified])
+	}
+	if *report != "" {
+		if err := s3viewer.SaveVerifyReport(results, *report); err != nil {
+			fatalf("Failed to save report: %v", err)
+		}
+		infof("Saved report into %v", *report)
+	}
+	if counts[s3viewer.DownloadFailed] > 0 || counts[s3viewer.VerifyMismatch] > 0 {
+		os.Exit(1)
+	}
+}
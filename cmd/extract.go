@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/hi-unc1e/s3viewer-go/s3viewer"
+	"os"
+	"text/tabwriter"
+)
+
+// runExtract 实现 `s3v extract`：从网页、JS、日志等内容里找出 bucket 地址
+func runExtract(args []string) {
+	fs := flag.NewFlagSet("extract", flag.ExitOnError)
+	urlsOnly := fs.Bool("urls", false, "only print the listing URLs, one per line, usable with -l")
+	jsonOutput := fs.Bool("json", false, "print the results as JSON")
+	crawl := fs.Bool("crawl", false, "crawl every extracted bucket and print a batch summary")
+	maxPage := fs.Int("p", 1, "max page per bucket when crawling")
+	workers := fs.Int("c", 8, "number of buckets crawled at the same time")
+	logging := addLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), s3viewer.T("Usage:")+" s3v extract [-urls|-json] [-crawl] (file | http(s)://page | -) ...")
+		fs.PrintDefaults()
+	}
+	parseFlags(fs, args, logging)
+
+	sources := fs.Args()
+	if len(sources) == 0 {
+		sources = []string{"-"}
+	}
+	ctx, stop := interruptContext()
+	defer stop()
+
+	var text string
+	for _, source := range sources {
+		content, err := s3viewer.ReadExtractSourceContext(ctx, source, os.Stdin)
+		if err != nil {
+			warnf("Failed to read %v: %v", source, err)
+			continue
+		}
+		text += content + "\n"
+	}
+	refs := s3viewer.ExtractBuckets(text)
+	infof("Buckets found: %v", len(refs))
+
+	switch {
+	case *crawl:
+		// Batch 只认 S3 的 XML 列表，Azure 返回的是 EnumerationResults，爬了也一定失败
+		var targets []string
+		skipped := 0
+		for _, ref := range refs {
+			switch {
+			case ref.Url == "":
+			case ref.Provider == "azure":
+				skipped++
+			default:
+				targets = append(targets, ref.Url)
+			}
+		}
+		if skipped > 0 {
+			warnf("Skipped %v Azure containers, their listing is not S3 XML", skipped)
+		}
+		results := (&s3viewer.Batch{Workers: *workers, MaxPage: *maxPage}).RunContext(ctx, targets)
+		if err := s3viewer.PrintBatchSummary(os.Stdout, results); err != nil {
+			fatalf("Failed to print summary: %v", err)
+		}
+	case *urlsOnly:
+		for _, ref := range refs {
+			if ref.Url != "" {
+				fmt.Println(ref.Url)
+			}
+		}
+	case *jsonOutput:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(refs); err != nil {
+			fatalf("Failed to write JSON: %v", err)
+		}
+	default:
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "Provider\tBucket\tRegion\tUrl\tMatch")
+		for _, ref := range refs {
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n", ref.Provider, ref.Bucket, ref.Region, ref.Url, ref.Match)
+		}
+		writer.Flush()
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/hi-unc1e/s3viewer-go/s3viewer"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// stringList 可重复指定的参数，例如 -include '*.pdf' -include '*.docx'
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// filterFlags 各个子命令共用的过滤参数
+type filterFlags struct {
+	include stringList
+	exclude stringList
+	match   string
+	ext     string
+	minSize string
+	maxSize string
+	since   string
+	until   string
+	noDirs  bool
+}
+
+func addFilterFlags(fs *flag.FlagSet) *filterFlags {
+	ff := new(filterFlags)
+	fs.Var(&ff.include, "include", "only keep keys matching the glob, can be repeated, e.g. '*.pdf' or 'backup/**'")
+	fs.Var(&ff.exclude, "exclude", "drop keys matching the glob, can be repeated")
+	fs.StringVar(&ff.match, "match", "", "only keep keys matching the regex")
+	fs.StringVar(&ff.ext, "ext", "", "only keep these extensions, e.g. pdf,xlsx,sql")
+	fs.StringVar(&ff.minSize, "min-size", "", "minimum object size, e.g. 10K, 1.5MB")
+	fs.StringVar(&ff.maxSize, "max-size", "", "maximum object size, e.g. 100MB")
+	fs.StringVar(&ff.since, "since", "", "only keep objects modified since, e.g. 2024-01-01, RFC3339 or 7d")
+	fs.StringVar(&ff.until, "until", "", "only keep objects modified until, e.g. 2024-06-30, RFC3339 or 24h")
+	fs.BoolVar(&ff.noDirs, "no-dirs", false, "drop zero-byte `folder/` markers")
+	return ff
+}
+
+// Filter 把命令行参数转换成 s3viewer.Filter，没有指定任何条件时返回 nil
+func (ff *filterFlags) Filter() (*s3viewer.Filter, error) {
+	f := &s3viewer.Filter{
+		Include: ff.include,
+		Exclude: ff.exclude,
+		NoDirs:  ff.noDirs,
+	}
+	var err error
+	if ff.match != "" {
+		if f.Match, err = regexp.Compile(ff.match); err != nil {
+			return nil, fmt.Errorf("invalid -match: %w", err)
+		}
+	}
+	if ff.ext != "" {
+		f.Exts = strings.Split(ff.ext, ",")
+	}
+	if f.MinSize, err = s3viewer.ParseSize(ff.minSize); err != nil {
+		return nil, fmt.Errorf("invalid -min-size: %w", err)
+	}
+	if f.MaxSize, err = s3viewer.ParseSize(ff.maxSize); err != nil {
+		return nil, fmt.Errorf("invalid -max-size: %w", err)
+	}
+	now := time.Now()
+	if f.Since, err = s3viewer.ParseTimeArg(ff.since, now); err != nil {
+		return nil, fmt.Errorf("invalid -since: %w", err)
+	}
+	if f.Until, err = s3viewer.ParseUntilArg(ff.until, now); err != nil {
+		return nil, fmt.Errorf("invalid -until: %w", err)
+	}
+	if err := f.Compile(); err != nil {
+		return nil, err
+	}
+
+	if len(f.Include) == 0 && len(f.Exclude) == 0 && f.Match == nil && len(f.Exts) == 0 &&
+		f.MinSize == 0 && f.MaxSize == 0 && f.Since.IsZero() && f.Until.IsZero() && !f.NoDirs {
+		return nil, nil
+	}
+	return f, nil
+}
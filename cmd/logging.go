@@ -0,0 +1,114 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/hi-unc1e/s3viewer-go/s3viewer"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// logFlags 各个子命令共用的日志、语言参数；数据写到 stdout，日志一律写到 stderr
+type logFlags struct {
+	verbose bool
+	quiet   bool
+	format  string
+	lang    string
+}
+
+func addLogFlags(fs *flag.FlagSet) *logFlags {
+	lf := new(logFlags)
+	fs.BoolVar(&lf.verbose, "v", false, "verbose, also log every request and page")
+	fs.BoolVar(&lf.quiet, "q", false, "quiet, hide info logs")
+	fs.StringVar(&lf.format, "log-format", "text", "log format: text or json")
+	fs.StringVar(&lf.lang, "lang", "", "language of messages: en or zh (default from LANG)")
+	return lf
+}
+
+// parseFlags 解析参数并初始化语言和日志。-lang 要在解析之前确定，-h 打印的帮助才是对应的语言
+func parseFlags(fs *flag.FlagSet, args []string, lf *logFlags) {
+	lang := langFromArgs(args)
+	if lang == "" {
+		lang = s3viewer.DetectLang()
+	}
+	if err := s3viewer.SetLang(lang); err != nil {
+		fmt.Fprintln(os.Stderr, s3viewer.T("Invalid -lang: %v", lang))
+		os.Exit(2)
+	}
+	fs.VisitAll(func(f *flag.Flag) {
+		f.Usage = s3viewer.T(f.Usage)
+	})
+	fs.Parse(args)
+	lf.setup()
+}
+
+// langFromArgs 从还没解析的参数里找 -lang
+func langFromArgs(args []string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "lang" {
+			continue
+		}
+		if hasValue {
+			return value
+		}
+		if i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// setup 按参数创建日志，同时作为 slog、log 包和 s3viewer 库的默认日志
+func (lf *logFlags) setup() {
+	level := slog.LevelInfo
+	switch {
+	case lf.quiet:
+		level = slog.LevelWarn
+	case lf.verbose:
+		level = slog.LevelDebug
+	}
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch lf.format {
+	case "json":
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	case "text", "":
+		// 终端里看，时间只保留时分秒
+		opts.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.String(a.Key, a.Value.Time().Format("15:04:05"))
+			}
+			return a
+		}
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	default:
+		fmt.Fprintln(os.Stderr, s3viewer.T("Invalid -log-format: %v", lf.format))
+		os.Exit(2)
+	}
+
+	logger := slog.New(handler)
+	slog.SetDefault(logger)
+	s3viewer.SetLogger(logger)
+}
+
+// infof 按当前语言记一条 info 日志，-q 时不输出
+func infof(format string, args ...any) {
+	slog.Info(s3viewer.T(format, args...))
+}
+
+// warnf 按当前语言记一条 warn 日志，-q 时也能看到
+func warnf(format string, args ...any) {
+	slog.Warn(s3viewer.T(format, args...))
+}
+
+// fatalf 按当前语言记一条 error 日志后退出，-q 时也能看到
+func fatalf(format string, args ...any) {
+	slog.Error(s3viewer.T(format, args...))
+	os.Exit(1)
+}
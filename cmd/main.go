@@ -1,73 +1,365 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"github.com/hi-unc1e/s3viewer-go/s3viewer"
 	"github.com/hi-unc1e/s3viewer-go/web"
-	"log"
 	"os"
+	"slices"
+	"strings"
 )
 
 func main() {
+	// 子命令
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "download":
+			runDownload(os.Args[2:])
+			return
+		case "diff":
+			runDiff(os.Args[2:])
+			return
+		case "discover":
+			runDiscover(os.Args[2:])
+			return
+		case "extract":
+			runExtract(os.Args[2:])
+			return
+		case "watch":
+			runWatch(os.Args[2:])
+			return
+		}
+	}
+
 	// 定义命令行参数
-	url := flag.String("u", "http://", "s3 URL, such as http://bucket.s3.amazonaws.com/")
-	output := flag.String("o", "", "output file name")
+	url := flag.String("u", "http://", "s3 URL, such as http://bucket.s3.amazonaws.com/, s3://bucket/prefix or oss://bucket, or a saved XML/HTML listing file")
+	endpoint := flag.String("endpoint", "", "endpoint for s3:// oss:// cos:// URLs or path-style hosts, e.g. http://127.0.0.1:9000")
+	var outputs stringList
+	flag.Var(&outputs, "o", "output file, format by extension (.csv .json .jsonl .txt); can be repeated, - prints the table and format:path forces a format, e.g. -o - -o all.csv -o all.json")
 	maxPage := flag.Int("p", 1, "max page")
-	webFlag := flag.Bool("web", false, "preview via local_web, such as http://127.0.0.1:30028/static/index.html")
-	flag.Parse()
-	// 2nd param
-	isUseFileOutput := *output != ""
-	isRecursively := *maxPage > 0
+	webFlag := flag.Bool("web", false, "browse the result in a local web page, see -listen")
+	listen := flag.String("listen", web.DefaultListen, "address of the web page, implies -web")
+	previewMaxSize := flag.String("preview-max-size", "20MB", "largest object the web page previews through its proxy")
+	sortBy := flag.String("sort", "", "sort by key|size|date")
+	reverse := flag.Bool("reverse", false, "reverse the sort order, e.g. -sort date -reverse for newest first")
+	human := flag.Bool("human", false, "print sizes in KiB/MiB/GiB")
+	columns := flag.String("columns", "Key,Size,LastModified", "columns to print: Key,Size,LastModified,Link,ETag,ContentType,DetectedType,TypeMismatch,Category,Severity")
+	classify := flag.Bool("classify", false, "classify sensitive files by key (backups, credentials, PII, ...) and print a summary")
+	rules := flag.String("rules", "", "YAML file with extra classification rules, implies -classify")
+	scan := flag.Bool("scan", false, "download documents (docx/xlsx/pptx/pdf/txt/csv/...) and scan their content for secrets and PII")
+	scanMaxSize := flag.String("scan-max-size", "10MB", "skip objects larger than this when scanning")
+	scanReport := flag.String("scan-report", "", "save the scan report to this file (.csv or .json)")
+	sniff := flag.String("sniff", "", "detect content types: head (HEAD only) or range (HEAD + first 512 bytes)")
+	archives := flag.Bool("archives", false, "list files inside .zip/.tar/.tar.gz objects as archive.zip!/inner/path")
+	archiveMaxEntries := flag.Int("archive-max-entries", 1000, "max entries to list per archive")
+	list := flag.String("l", "", "file with one target URL per line, or - for stdin (batch mode)")
+	workers := flag.Int("c", 8, "number of targets crawled at the same time in batch mode")
+	hostRate := flag.Float64("host-rate", 1, "max requests per second to the same host in batch mode, including every listing page (0 = unlimited)")
+	outDir := flag.String("outdir", "", "save each target's result into this directory in batch mode")
+	outFormat := flag.String("outdir-format", "csv", "format of the per-target results: csv or json")
+	summary := flag.String("summary", "", "save the batch summary to this file (.csv or .json)")
+	compareWith := flag.String("compare-with", "", "previous export (.csv or .json) to diff this crawl against")
+	diffOutput := flag.String("diff-o", "", "save the -compare-with diff to this file (.json, .html or table text)")
+	var pages stringList
+	flag.Var(&pages, "f", "saved listing page (XML or HTML) for offline analysis, can be a glob and repeated, e.g. -f 'pages/*.xml'; with -u the links are filled from it")
+	stream := flag.Bool("stream", false, "write -o (.csv or .jsonl) page by page while crawling instead of keeping every page in memory")
+	filters := addFilterFlags(flag.CommandLine)
+	logging := addLogFlags(flag.CommandLine)
+	parseFlags(flag.CommandLine, os.Args[1:], logging)
 
 	// 检查是否提供了所有必需的参数
 	if len(os.Args) < 2 {
-		fmt.Println("Usage: s3viewer -u s3_url [-o output_file] [-p max_page]")
+		fmt.Fprintln(os.Stderr, s3viewer.T("Usage:")+" s3viewer -u s3_url [-o output_file] [-p max_page]")
+		fmt.Fprintln(os.Stderr, "       s3viewer -f 'pages/*.xml' [-u s3_url] [-o output_file]")
+		fmt.Fprintln(os.Stderr, "       s3viewer download -u s3_url [-d dir] [-c workers]")
+		fmt.Fprintln(os.Stderr, "       s3viewer -l targets.txt [-c workers] [-outdir dir] [-summary summary.csv]")
+		fmt.Fprintln(os.Stderr, "       s3viewer diff old.csv new.csv")
+		fmt.Fprintln(os.Stderr, "       s3viewer extract [-urls] [-crawl] app.js page.html ...")
+		fmt.Fprintln(os.Stderr, "       s3viewer discover -k keyword [-t https://{name}.s3.amazonaws.com]")
+		fmt.Fprintln(os.Stderr, "       s3viewer watch -u s3_url [-interval 10m] [-webhook url]")
 		return
 	}
 
-	if *url == "" {
-		log.Fatalf("s3 URL is required")
+	if *list != "" {
+		ctx, stop := interruptContext()
+		defer stop()
+		runBatch(ctx, batchOptions{
+			list: *list, endpoint: *endpoint, workers: *workers, hostRate: *hostRate, maxPage: *maxPage,
+			outDir: *outDir, format: *outFormat, summary: *summary,
+			classify: *classify, rules: *rules, filters: filters,
+		})
+		return
 	}
 
-	// 从远程 URL 加载内容
-	var result = new(s3viewer.ListBucketResult)
+	ctx, stop := interruptContext()
+	defer stop()
+
+	// -f 离线分析时 -u 可以不写，写了就用来拼下载链接
+	useURL := len(pages) == 0 || isFlagSet(flag.CommandLine, "u")
+	if useURL {
+		if *url == "" {
+			fatalf("s3 URL is required")
+		}
+		normalized, err := s3viewer.NormalizeLocation(*url, *endpoint)
+		if err != nil {
+			fatalf("Invalid -u: %v", err)
+		}
+		*url = normalized
+	}
 
-	// 初始化 URL
-	result.Url = *url
-	var err error
+	if isFlagSet(flag.CommandLine, "listen") {
+		*webFlag = true
+	}
+	maxPreview, err := s3viewer.ParseSize(*previewMaxSize)
+	if err != nil {
+		fatalf("Invalid -preview-max-size: %v", err)
+	}
 
-	if isRecursively {
-		result, err = s3viewer.LoadRemoteHTTPRecursive(*url, *maxPage)
+	printColumns, err := s3viewer.ParseColumns(*columns)
+	if err != nil {
+		fatalf("Invalid -columns: %v", err)
+	}
+	filter, err := filters.Filter()
+	if err != nil {
+		fatalf("Invalid filter: %v", err)
+	}
 
-		if err != nil {
-			log.Fatalf("Failed to load remote URL: %v", err)
+	if *stream {
+		if len(pages) > 0 {
+			fatalf("-stream can not be used with -f")
 		}
-	} else {
-		result, err = s3viewer.LoadRemoteHTTP(*url)
+		// 这些功能需要完整的列表，不能边爬边写
+		for _, name := range []string{"sort", "sniff", "classify", "rules", "scan", "scan-report", "archives", "compare-with", "web", "listen", "preview-max-size"} {
+			if isFlagSet(flag.CommandLine, name) {
+				fatalf("-stream can not be used with -%v", name)
+			}
+		}
+		if len(outputs) == 0 {
+			// 表格要等到最后才能对齐，边爬边写默认输出 JSONL
+			outputs = stringList{"jsonl"}
+		}
+		sink, err := openSinks(outputs, s3viewer.PrintOptions{Columns: printColumns, Human: *human})
 		if err != nil {
-			log.Fatalf("Failed to load remote URL: %v", err)
+			fatalf("%v", err)
+		}
+		if err := streamResult(ctx, *url, *maxPage, sink, filter); err != nil && ctx.Err() == nil {
+			fatalf("Failed to stream result: %v", err)
 		}
+		logSaved(outputs)
+		return
 	}
 
-	if isUseFileOutput {
-		// 保存结果到文件
-		if err := s3viewer.SaveResultToCSVFile(result, *output); err != nil {
-			log.Fatalf("Failed to save result to CSV file: %v", err)
+	// 从 -u 加载内容，S3 URL、本地文件等由对应的 Backend 处理
+	backend, _ := s3viewer.LookupBackend("file")
+	if useURL {
+		if backend, err = s3viewer.BackendFor(*url); err != nil {
+			fatalf("Invalid -u: %v", err)
+		}
+	}
+	caps := backend.Capabilities()
+	if !caps.Download && (*sniff != "" || *scan || *scanReport != "") {
+		fatalf("-sniff and -scan need downloadable objects, %v does not support them", backend.Name())
+	}
+	if !caps.Range && *archives {
+		fatalf("-archives needs Range requests, %v does not support them", backend.Name())
+	}
+
+	var result *s3viewer.ListBucketResult
+	if len(pages) > 0 {
+		if result, err = s3viewer.LoadFiles(pages...); err != nil {
+			fatalf("Failed to load -f: %v", err)
+		}
+		infof("Loaded %v objects from saved pages", len(result.Files))
+		if useURL {
+			result.MergeUrlAndFillLinks(*url)
 		}
-		log.Printf("Saved into %v", *output)
 	} else {
-		// 打印结果到终端
-		if err := s3viewer.PrintResult(result); err != nil {
-			log.Fatalf("Failed to print result: %v", err)
+		result, err = s3viewer.LoadContext(ctx, *url, *maxPage)
+		// 中断时继续往下走，把已经拉取到的部分写到 -o
+		if err != nil && ctx.Err() == nil {
+			fatalf("Failed to load remote URL: %v", err)
+		}
+	}
+
+	// 压缩包里的文件追加到列表里，后面的过滤、分类都能看到
+	if *archives {
+		total := len(result.Files)
+		lister := &s3viewer.ArchiveLister{MaxEntries: *archiveMaxEntries}
+		result.Files = lister.ExpandContext(ctx, result.Files)
+		infof("Objects inside archives: %v", len(result.Files)-total)
+	}
+
+	// 过滤对 CSV、终端、web 输出都生效
+	if filter != nil {
+		total := len(result.Files)
+		if result, err = s3viewer.FilterResult(result, filter); err != nil {
+			fatalf("Failed to filter result: %v", err)
 		}
+		infof("%v/%v objects left after filtering", len(result.Files), total)
 	}
 
-	if *webFlag {
-		imageUrls := make([]string, 0)
+	if *sniff != "" {
+		if *sniff != "head" && *sniff != "range" {
+			fatalf("Invalid -sniff: %v", *sniff)
+		}
+		sniffer := &s3viewer.Sniffer{Range: *sniff == "range"}
+		failed := sniffer.EnrichContext(ctx, result.Files)
+		mismatches := 0
 		for _, file := range result.Files {
-			imageUrls = append(imageUrls, file.Link)
+			if file.TypeMismatch {
+				mismatches++
+			}
 		}
-		web.ServeHttp(imageUrls)
+		infof("Sniffed %v objects, %v failed, %v with mismatched extension", len(result.Files), failed, mismatches)
 	}
+
+	if *classify || *rules != "" {
+		classifier, err := loadClassifier(*rules)
+		if err != nil {
+			fatalf("%v", err)
+		}
+		classifier.ClassifyFiles(result.Files)
+		// 没有指定 -columns 时，终端输出里带上分类
+		if !isFlagSet(flag.CommandLine, "columns") {
+			printColumns = append(printColumns, s3viewer.ColumnCategory, s3viewer.ColumnSeverity)
+		}
+		if err := s3viewer.PrintCategorySummary(os.Stderr, result.Files); err != nil {
+			fatalf("Failed to print summary: %v", err)
+		}
+	}
+
+	if *scan || *scanReport != "" {
+		maxSize, err := s3viewer.ParseSize(*scanMaxSize)
+		if err != nil {
+			fatalf("Invalid -scan-max-size: %v", err)
+		}
+		scanner := &s3viewer.Scanner{MaxSize: maxSize}
+		results := scanner.ScanContext(ctx, result.Files)
+		hits := 0
+		for _, r := range results {
+			if len(r.Findings) > 0 {
+				hits++
+			}
+		}
+		infof("Scanned %v objects, %v with findings", len(results), hits)
+		if *scanReport != "" {
+			if err := s3viewer.SaveScanReport(results, *scanReport); err != nil {
+				fatalf("Failed to save scan report: %v", err)
+			}
+			infof("Saved scan report into %v", *scanReport)
+		} else if hits > 0 {
+			if err := s3viewer.PrintScanReport(os.Stderr, results); err != nil {
+				fatalf("Failed to print scan report: %v", err)
+			}
+		}
+	}
+
+	if *compareWith != "" {
+		previous, err := s3viewer.LoadSnapshot(*compareWith)
+		if err != nil {
+			fatalf("Failed to load %v: %v", *compareWith, err)
+		}
+		d := s3viewer.Diff(previous, result)
+		if *diffOutput != "" {
+			if err := s3viewer.SaveDiff(d, *diffOutput, fmt.Sprintf("%v -> %v", *compareWith, *url)); err != nil {
+				fatalf("Failed to save diff: %v", err)
+			}
+			infof("Saved diff into %v", *diffOutput)
+		} else if err := s3viewer.PrintDiff(os.Stderr, d); err != nil {
+			fatalf("Failed to print diff: %v", err)
+		}
+	}
+
+	if err := s3viewer.SortFiles(result.Files, *sortBy, *reverse); err != nil {
+		fatalf("Invalid -sort: %v", err)
+	}
+
+	// 没有 -o 时打印表格到终端；多个 -o 时一次写出所有格式
+	if len(outputs) == 0 {
+		outputs = stringList{"-"}
+	}
+	sink, err := openSinks(outputs, s3viewer.PrintOptions{Columns: printColumns, Human: *human})
+	if err != nil {
+		fatalf("%v", err)
+	}
+	if err := s3viewer.WriteResult(sink, result); err != nil {
+		fatalf("Failed to save result: %v", err)
+	}
+	logSaved(outputs)
+
+	if *webFlag && ctx.Err() == nil {
+		// web 服务一直运行，交还 Ctrl-C 的默认处理
+		stop()
+		server := web.NewServer(result)
+		server.MaxPreviewSize = maxPreview
+		if err := server.ListenAndServe(*listen); err != nil {
+			fatalf("Failed to start the server: %v", err)
+		}
+	}
+}
+
+// streamResult 实现 -stream：每拉到一页就写给 sink，中断时已经写出的部分保留
+func streamResult(ctx context.Context, url string, maxPage int, sink s3viewer.Sink, filter *s3viewer.Filter) error {
+	written, err := s3viewer.StreamResult(ctx, s3viewer.NewLister(url, maxPage), sink, filter)
+	infof("Saved %v objects", written)
+	return err
+}
+
+// openSinks 打开所有 -o，见 s3viewer.OpenSink
+func openSinks(outputs []string, opts s3viewer.PrintOptions) (s3viewer.Sink, error) {
+	var sinks []s3viewer.Sink
+	for _, output := range outputs {
+		sink, err := s3viewer.OpenSink(output, os.Stdout, opts)
+		if err != nil {
+			for _, opened := range sinks {
+				opened.End()
+			}
+			return nil, fmt.Errorf("Invalid -o %v: %w", output, err)
+		}
+		sinks = append(sinks, sink)
+	}
+	return s3viewer.MultiSink(sinks...), nil
+}
+
+// logSaved 打印写到文件的 -o
+func logSaved(outputs []string) {
+	names := s3viewer.SinkNames()
+	for _, output := range outputs {
+		if output == "-" || slices.Contains(names, output) {
+			continue
+		}
+		if name, path, ok := strings.Cut(output, ":"); ok && slices.Contains(names, name) {
+			output = path
+		}
+		infof("Saved into %v", output)
+	}
+}
+
+// loadClassifier 内置规则加上 rulesPath 里的规则，rulesPath 为空时只用内置规则
+func loadClassifier(rulesPath string) (*s3viewer.Classifier, error) {
+	var extra []s3viewer.Rule
+	if rulesPath != "" {
+		var err error
+		if extra, err = s3viewer.LoadRules(rulesPath); err != nil {
+			return nil, fmt.Errorf("Failed to load rules: %w", err)
+		}
+	}
+	classifier, err := s3viewer.NewClassifier(extra)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid rules: %w", err)
+	}
+	return classifier, nil
+}
+
+// isFlagSet 判断命令行里是否显式指定了某个参数
+func isFlagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
 }
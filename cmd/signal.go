@@ -0,0 +1,26 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// interruptContext 返回收到 Ctrl-C（或 SIGTERM）时取消的 ctx。
+// 第一次中断后恢复默认的信号处理，正在保存结果时再按一次 Ctrl-C 可以直接退出
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-signals:
+			infof("Interrupted, no new requests, saving what we have (Ctrl-C again to quit now)")
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(signals)
+	}()
+	return ctx, cancel
+}
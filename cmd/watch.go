@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/hi-unc1e/s3viewer-go/s3viewer"
+	"os"
+	"time"
+)
+
+// runWatch 实现 `s3v watch`：定期爬取 bucket，把新增、删除、修改的对象以 JSONL 输出
+func runWatch(args []string) {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	url := fs.String("u", "", "s3 URL, such as http://bucket.s3.amazonaws.com/, s3://bucket/prefix or oss://bucket")
+	endpoint := fs.String("endpoint", "", "endpoint for s3:// oss:// cos:// URLs or path-style hosts")
+	maxPage := fs.Int("p", 10, "max page per crawl")
+	interval := fs.Duration("interval", 10*time.Minute, "time between crawls")
+	state := fs.String("state", "", "file keeping the last crawl (.json or .csv), so restarts do not lose history")
+	webhook := fs.String("webhook", "", "POST each batch of events as a JSON array to this URL")
+	rounds := fs.Int("n", 0, "stop after this many crawls (0 = forever)")
+	logging := addLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), s3viewer.T("Usage:")+" s3v watch -u s3_url [-interval 10m] [-state state.json] [-webhook url]")
+		fs.PrintDefaults()
+	}
+	parseFlags(fs, args, logging)
+
+	if *url == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+	normalized, err := s3viewer.NormalizeURL(*url, *endpoint)
+	if err != nil {
+		fatalf("Invalid -u: %v", err)
+	}
+	if *interval <= 0 {
+		fatalf("Invalid -interval: %v", *interval)
+	}
+
+	watcher := &s3viewer.Watcher{Url: normalized, MaxPage: *maxPage, StatePath: *state, Webhook: *webhook}
+	ctx, stop := interruptContext()
+	defer stop()
+	if err := watcher.RunContext(ctx, os.Stdout, *interval, *rounds); err != nil {
+		fatalf("Failed to write events: %v", err)
+	}
+}
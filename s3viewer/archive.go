@@ -0,0 +1,362 @@
+package s3viewer
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ArchiveSeparator 压缩包内文件的伪 Key 格式：archive.zip!/inner/path
+const ArchiveSeparator = "!/"
+
+// zip 格式的签名和固定长度，参考 APPNOTE.TXT
+const (
+	zipEOCDSignature      = 0x06054b50
+	zipEOCDLen            = 22
+	zip64LocatorSignature = 0x07064b50
+	zip64LocatorLen       = 20
+	zip64EOCDSignature    = 0x06064b50
+	zip64EOCDLen          = 56
+	zipCDSignature        = 0x02014b50
+	zipCDHeaderLen        = 46
+	zipMaxCommentLen      = 65535
+)
+
+// ArchiveEntry 压缩包里的一个文件
+type ArchiveEntry struct {
+	Name           string
+	Size           int64 // 解压后的大小
+	CompressedSize int64 // tar 包里为 0
+	Modified       time.Time
+}
+
+// ArchiveLister 不完整下载压缩包，列出里面的文件
+// zip 用 Range 读取末尾的目录区；tar/tar.gz 边下边解析，到上限就断开
+type ArchiveLister struct {
+	Client     *http.Client // 为 nil 时使用 NewHTTPClient(2min)
+	MaxEntries int          // 每个压缩包最多列出多少个文件，<=0 时为 1000
+	MaxBytes   int64        // 流式读取 tar/tar.gz 时最多下载的字节数，<=0 时为 100MiB
+	MaxDirSize int64        // zip 目录区的大小上限，<=0 时为 16MiB
+}
+
+// IsArchive 判断 Key 是否是支持列目录的压缩包
+func IsArchive(key string) bool {
+	return archiveFormat(key) != ""
+}
+
+func archiveFormat(key string) string {
+	lower := strings.ToLower(key)
+	switch {
+	case strings.HasSuffix(lower, ".zip"), strings.HasSuffix(lower, ".jar"), strings.HasSuffix(lower, ".war"):
+		return "zip"
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return "tar.gz"
+	case strings.HasSuffix(lower, ".tar"):
+		return "tar"
+	}
+	return ""
+}
+
+func (l *ArchiveLister) client() *http.Client {
+	if l.Client != nil {
+		return l.Client
+	}
+	return NewHTTPClient(2 * time.Minute)
+}
+
+func (l *ArchiveLister) maxEntries() int {
+	if l.MaxEntries > 0 {
+		return l.MaxEntries
+	}
+	return 1000
+}
+
+// List 列出压缩包里的文件
+func (l *ArchiveLister) List(file File) ([]ArchiveEntry, error) {
+	return l.ListContext(context.Background(), file)
+}
+
+// ListContext 同 List，ctx 取消时请求随之中断
+func (l *ArchiveLister) ListContext(ctx context.Context, file File) ([]ArchiveEntry, error) {
+	return l.list(ctx, l.client(), file)
+}
+
+func (l *ArchiveLister) list(ctx context.Context, client *http.Client, file File) ([]ArchiveEntry, error) {
+	switch archiveFormat(file.Key) {
+	case "zip":
+		return l.listZip(ctx, client, file)
+	case "tar.gz":
+		return l.listTar(ctx, client, file, true)
+	case "tar":
+		return l.listTar(ctx, client, file, false)
+	}
+	return nil, fmt.Errorf("unsupported archive: %v", file.Key)
+}
+
+// Expand 把压缩包里的文件作为伪 Key（archive.zip!/inner/path）追加到列表后面，方便分类和过滤
+// 单个压缩包失败只打日志，不影响其他文件
+func (l *ArchiveLister) Expand(files []File) []File {
+	return l.ExpandContext(context.Background(), files)
+}
+
+// ExpandContext 同 Expand，ctx 取消后不再读取剩下的压缩包
+func (l *ArchiveLister) ExpandContext(ctx context.Context, files []File) []File {
+	client := l.client()
+	expanded := files
+	for _, file := range files {
+		if ctx.Err() != nil {
+			break
+		}
+		if !IsArchive(file.Key) || file.Link == "" || file.Archive != "" {
+			continue
+		}
+		entries, err := l.list(ctx, client, file)
+		if err != nil {
+			Logger().Warn(T("failed to list archive"), "key", file.Key, "err", err)
+			continue
+		}
+		for _, entry := range entries {
+			expanded = append(expanded, File{
+				Key:          file.Key + ArchiveSeparator + entry.Name,
+				Size:         int(entry.Size),
+				LastModified: entry.Modified,
+				Link:         file.Link,
+				Archive:      file.Key,
+			})
+		}
+	}
+	return expanded
+}
+
+// listZip 先读末尾的 EOCD（zip64 时再读 zip64 EOCD），再按偏移读取整个目录区
+func (l *ArchiveLister) listZip(ctx context.Context, client *http.Client, file File) ([]ArchiveEntry, error) {
+	tail, total, err := fetchRange(ctx, client, file.Link, fmt.Sprintf("bytes=-%d", zipEOCDLen+zipMaxCommentLen+zip64LocatorLen))
+	if err != nil {
+		return nil, err
+	}
+	tailStart := total - int64(len(tail))
+
+	eocd := bytes.LastIndex(tail, le32(zipEOCDSignature))
+	if eocd < 0 || len(tail)-eocd < zipEOCDLen {
+		return nil, fmt.Errorf("end of central directory not found")
+	}
+	rec := tail[eocd:]
+	entries := uint64(binary.LittleEndian.Uint16(rec[10:]))
+	dirSize := uint64(binary.LittleEndian.Uint32(rec[12:]))
+	dirOffset := uint64(binary.LittleEndian.Uint32(rec[16:]))
+
+	// zip64：EOCD 前面是 zip64 locator，里面记录了 zip64 EOCD 的位置
+	if entries == 0xffff || dirSize == 0xffffffff || dirOffset == 0xffffffff {
+		loc := eocd - zip64LocatorLen
+		if loc < 0 || binary.LittleEndian.Uint32(tail[loc:]) != zip64LocatorSignature {
+			return nil, fmt.Errorf("zip64 locator not found")
+		}
+		recOffset := int64(binary.LittleEndian.Uint64(tail[loc+8:]))
+		rec64, err := sliceOrFetch(ctx, client, file.Link, tail, tailStart, recOffset, zip64EOCDLen)
+		if err != nil {
+			return nil, err
+		}
+		if binary.LittleEndian.Uint32(rec64) != zip64EOCDSignature {
+			return nil, fmt.Errorf("invalid zip64 end of central directory")
+		}
+		entries = binary.LittleEndian.Uint64(rec64[32:])
+		dirSize = binary.LittleEndian.Uint64(rec64[40:])
+		dirOffset = binary.LittleEndian.Uint64(rec64[48:])
+	}
+
+	maxDirSize := l.MaxDirSize
+	if maxDirSize <= 0 {
+		maxDirSize = 16 << 20
+	}
+	if int64(dirSize) > maxDirSize {
+		return nil, fmt.Errorf("central directory too large: %v", dirSize)
+	}
+	dir, err := sliceOrFetch(ctx, client, file.Link, tail, tailStart, int64(dirOffset), int64(dirSize))
+	if err != nil {
+		return nil, err
+	}
+	return parseZipCentralDirectory(dir, int(min(entries, uint64(l.maxEntries()))))
+}
+
+// parseZipCentralDirectory 解析目录区里的文件记录
+func parseZipCentralDirectory(dir []byte, maxEntries int) ([]ArchiveEntry, error) {
+	var entries []ArchiveEntry
+	for len(dir) >= zipCDHeaderLen && len(entries) < maxEntries {
+		if binary.LittleEndian.Uint32(dir) != zipCDSignature {
+			return entries, fmt.Errorf("invalid central directory header")
+		}
+		modTime := binary.LittleEndian.Uint16(dir[12:])
+		modDate := binary.LittleEndian.Uint16(dir[14:])
+		compressed := uint64(binary.LittleEndian.Uint32(dir[20:]))
+		size := uint64(binary.LittleEndian.Uint32(dir[24:]))
+		nameLen := int(binary.LittleEndian.Uint16(dir[28:]))
+		extraLen := int(binary.LittleEndian.Uint16(dir[30:]))
+		commentLen := int(binary.LittleEndian.Uint16(dir[32:]))
+		end := zipCDHeaderLen + nameLen + extraLen + commentLen
+		if len(dir) < end {
+			return entries, fmt.Errorf("truncated central directory")
+		}
+		name := string(dir[zipCDHeaderLen : zipCDHeaderLen+nameLen])
+		extra := dir[zipCDHeaderLen+nameLen : zipCDHeaderLen+nameLen+extraLen]
+
+		// zip64 扩展字段：大小超过 4GiB 时真实值放在这里，顺序是 size、compressed
+		for len(extra) >= 4 {
+			tag := binary.LittleEndian.Uint16(extra)
+			n := int(binary.LittleEndian.Uint16(extra[2:]))
+			if len(extra) < 4+n {
+				break
+			}
+			if tag == 0x0001 {
+				field := extra[4 : 4+n]
+				if size == 0xffffffff && len(field) >= 8 {
+					size = binary.LittleEndian.Uint64(field)
+					field = field[8:]
+				}
+				if compressed == 0xffffffff && len(field) >= 8 {
+					compressed = binary.LittleEndian.Uint64(field)
+				}
+			}
+			extra = extra[4+n:]
+		}
+
+		entries = append(entries, ArchiveEntry{
+			Name:           name,
+			Size:           int64(size),
+			CompressedSize: int64(compressed),
+			Modified:       msDosTime(modDate, modTime),
+		})
+		dir = dir[end:]
+	}
+	return entries, nil
+}
+
+// msDosTime 转换 zip 里的 MS-DOS 日期时间
+func msDosTime(date, t uint16) time.Time {
+	if date == 0 {
+		return time.Time{}
+	}
+	return time.Date(
+		int(date>>9)+1980, time.Month(date>>5&0xf), int(date&0x1f),
+		int(t>>11), int(t>>5&0x3f), int(t&0x1f)*2, 0, time.UTC,
+	)
+}
+
+// listTar 流式读取 tar/tar.gz 的文件头，到 MaxEntries 或 MaxBytes 就停下
+func (l *ArchiveLister) listTar(ctx context.Context, client *http.Client, file File, gzipped bool) ([]ArchiveEntry, error) {
+	req, err := NewRequestContext(ctx, "GET", file.Link)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET: %v", resp.Status)
+	}
+
+	maxBytes := l.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = 100 << 20
+	}
+	var r io.Reader = io.LimitReader(resp.Body, maxBytes)
+	if gzipped {
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	var entries []ArchiveEntry
+	tr := tar.NewReader(r)
+	for len(entries) < l.maxEntries() {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// 读到上限被截断，返回已经拿到的部分
+			if len(entries) > 0 && (err == io.ErrUnexpectedEOF || strings.Contains(err.Error(), "unexpected EOF")) {
+				Logger().Info(T("archive read limit reached"), "key", file.Key, "entries", len(entries))
+				break
+			}
+			return entries, err
+		}
+		name := header.Name
+		if header.Typeflag == tar.TypeDir && !strings.HasSuffix(name, "/") {
+			name += "/"
+		}
+		entries = append(entries, ArchiveEntry{Name: name, Size: header.Size, Modified: header.ModTime.UTC()})
+	}
+	return entries, nil
+}
+
+// fetchRange 发送 Range 请求，返回内容和对象总大小；服务端必须支持 Range
+func fetchRange(ctx context.Context, client *http.Client, link string, byteRange string) ([]byte, int64, error) {
+	req, err := NewRequestContext(ctx, "GET", link)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Range", byteRange)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent {
+		return nil, 0, fmt.Errorf("Range not supported: %v", resp.Status)
+	}
+
+	// Content-Range: bytes 100-199/1000
+	total := int64(-1)
+	if cr := resp.Header.Get("Content-Range"); cr != "" {
+		if i := strings.LastIndex(cr, "/"); i >= 0 {
+			if n, err := strconv.ParseInt(cr[i+1:], 10, 64); err == nil {
+				total = n
+			}
+		}
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+	if total < 0 {
+		return nil, 0, fmt.Errorf("invalid Content-Range: %v", resp.Header.Get("Content-Range"))
+	}
+	return data, total, nil
+}
+
+// sliceOrFetch 需要的区间已经在 tail 里就直接切片，否则再发一次 Range 请求
+func sliceOrFetch(ctx context.Context, client *http.Client, link string, tail []byte, tailStart int64, offset int64, length int64) ([]byte, error) {
+	if length == 0 {
+		return nil, nil
+	}
+	if offset >= tailStart && offset+length <= tailStart+int64(len(tail)) {
+		return tail[offset-tailStart : offset-tailStart+length], nil
+	}
+	data, _, err := fetchRange(ctx, client, link, fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) != length {
+		return nil, fmt.Errorf("short read: got %v bytes, want %v", len(data), length)
+	}
+	return data, nil
+}
+
+func le32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, v)
+	return b
+}
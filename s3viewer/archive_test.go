@@ -0,0 +1,111 @@
+package s3viewer
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestArchiveListZip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	modified := time.Date(2024, 6, 23, 11, 41, 2, 0, time.UTC)
+	// 放一个大的、不好压缩的文件，确认不会整个下载
+	big := make([]byte, 1<<20)
+	for i := range big {
+		big[i] = byte(i * 7 % 251)
+	}
+	for _, f := range []struct {
+		name    string
+		content []byte
+	}{
+		{"db/dump.sql", []byte("INSERT INTO users VALUES (1);")},
+		{"data.bin", big},
+		{"配置/.env", []byte("KEY=1")},
+	} {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Store, Modified: modified})
+		if err != nil {
+			t.Fatalf("Failed to create zip entry: %v", err)
+		}
+		w.Write(f.content)
+	}
+	zw.SetComment("backup made by s3viewer test")
+	zw.Close()
+
+	server := newObjectServer(t, map[string][]byte{"backup.zip": buf.Bytes()})
+	file := File{Key: "backup.zip", Size: buf.Len(), Link: server.URL + "/backup.zip"}
+
+	entries, err := (&ArchiveLister{}).List(file)
+	if err != nil {
+		t.Fatalf("Failed to list zip: %v", err)
+	}
+	assert.Len(t, entries, 3)
+	assert.Equal(t, "db/dump.sql", entries[0].Name)
+	assert.Equal(t, int64(len(big)), entries[1].Size)
+	assert.Equal(t, "配置/.env", entries[2].Name)
+	assert.Equal(t, modified, entries[0].Modified)
+
+	// 只读了末尾，没有下载整个压缩包
+	assert.Less(t, server.sent, 100*1024)
+	for _, r := range server.ranges {
+		assert.True(t, strings.HasPrefix(r, "bytes="), r)
+	}
+}
+
+func TestArchiveListTarGz(t *testing.T) {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for i := 0; i < 10; i++ {
+		content := []byte(fmt.Sprintf("file %d", i))
+		tw.WriteHeader(&tar.Header{Name: fmt.Sprintf("www/%d.php", i), Mode: 0644, Size: int64(len(content)), ModTime: time.Unix(1700000000, 0)})
+		tw.Write(content)
+	}
+	tw.Close()
+	gw.Close()
+
+	server := newObjectServer(t, map[string][]byte{"site.tar.gz": buf.Bytes()})
+	file := File{Key: "site.tar.gz", Size: buf.Len(), Link: server.URL + "/site.tar.gz"}
+
+	entries, err := (&ArchiveLister{MaxEntries: 4}).List(file)
+	if err != nil {
+		t.Fatalf("Failed to list tar.gz: %v", err)
+	}
+	assert.Len(t, entries, 4)
+	assert.Equal(t, "www/0.php", entries[0].Name)
+	assert.Equal(t, int64(6), entries[0].Size)
+}
+
+func TestArchiveExpand(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	zw.Create("db/users.sql")
+	zw.Create("readme.txt")
+	zw.Close()
+
+	server := newObjectServer(t, map[string][]byte{"old/backup.zip": buf.Bytes()})
+	files := []File{
+		{Key: "index.html", Size: 10, Link: server.URL + "/index.html"},
+		{Key: "old/backup.zip", Size: buf.Len(), Link: server.URL + "/old/backup.zip"},
+		{Key: "missing.zip", Size: 10, Link: server.URL + "/missing.zip"},
+	}
+
+	expanded := (&ArchiveLister{}).Expand(files)
+	assert.Len(t, expanded, 5)
+	inner := expanded[3]
+	assert.Equal(t, "old/backup.zip!/db/users.sql", inner.Key)
+	assert.Equal(t, "old/backup.zip", inner.Archive)
+	assert.Equal(t, files[1].Link, inner.Link)
+
+	// 分类和过滤都能看到压缩包里的文件
+	c, _ := NewClassifier(nil)
+	category, _ := c.Classify(inner.Key)
+	assert.Equal(t, "database", category)
+	assert.Equal(t, []string{"old/backup.zip!/db/users.sql"}, filterKeys(&Filter{Exts: []string{"sql"}}, expanded))
+}
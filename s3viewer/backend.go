@@ -0,0 +1,194 @@
+package s3viewer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Backend 一种列目录的来源。Lister 通过它逐页拉取，新的来源实现这个接口并在 init 里 RegisterBackend 即可，
+// 不需要改 cmd
+type Backend interface {
+	// Name 注册时使用的名字，例如 s3、file
+	Name() string
+	// ListPage 拉取 location 指向的一页，返回的 File 已经填好 Link
+	ListPage(ctx context.Context, location string) (*ListBucketResult, error)
+	// NextPage 根据当前页算出下一页的 location，没有下一页时返回 ""
+	NextPage(location string, page *ListBucketResult) (string, error)
+	// ObjectURL 对象的下载链接，不支持下载时返回 ""
+	ObjectURL(location string, key string) string
+	Capabilities() Capabilities
+}
+
+// Capabilities 来源支持的功能，cmd 据此判断 -sniff、-scan、download 等能不能用
+type Capabilities struct {
+	Paging   bool // 支持翻页
+	Download bool // ObjectURL 可以直接下载
+	Range    bool // 下载链接支持 Range 请求（-sniff range、-archives）
+}
+
+type backendEntry struct {
+	backend Backend
+	match   func(location string) bool
+}
+
+var backends []backendEntry
+
+// RegisterBackend 注册一个来源，match 判断 location 是否归它处理；按注册顺序匹配，先注册的优先
+func RegisterBackend(backend Backend, match func(location string) bool) {
+	backends = append(backends, backendEntry{backend: backend, match: match})
+}
+
+// BackendFor 返回处理 location 的来源
+func BackendFor(location string) (Backend, error) {
+	for _, entry := range backends {
+		if entry.match(location) {
+			return entry.backend, nil
+		}
+	}
+	return nil, fmt.Errorf("no backend for %v", location)
+}
+
+// LookupBackend 按名字查找来源
+func LookupBackend(name string) (Backend, bool) {
+	for _, entry := range backends {
+		if entry.backend.Name() == name {
+			return entry.backend, true
+		}
+	}
+	return nil, false
+}
+
+// BackendNames 已注册的来源，按注册顺序
+func BackendNames() []string {
+	names := make([]string, len(backends))
+	for i, entry := range backends {
+		names[i] = entry.backend.Name()
+	}
+	return names
+}
+
+// NormalizeLocation 本地文件等非 S3 来源原样返回，其余按 NormalizeURL 转换成列目录的 URL
+func NormalizeLocation(raw string, endpoint string) (string, error) {
+	if backend, err := BackendFor(raw); err == nil && backend.Name() != "s3" {
+		return raw, nil
+	}
+	return NormalizeURL(raw, endpoint)
+}
+
+func init() {
+	RegisterBackend(fileBackend{}, isLocalFile)
+	RegisterBackend(s3Backend{}, isS3URL)
+}
+
+// parseListing 从响应体（XML 或者嵌着 XML 的 HTML）里解析出列表
+func parseListing(body []byte) (*ListBucketResult, error) {
+	// 提取 <ListBucketResult> 标签及其内容
+	xmlContent, err := findS3XMLString(string(body))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to find S3 XML string: %w", err)
+	}
+
+	// 预处理 XML 内容
+	xmlContent = sanitizeXMLContent(xmlContent)
+
+	// 解析 XML 内容为对象；出错时也返回已经解析出来的部分
+	result, err := parseXMLToListBucketResult(xmlContent)
+	if err != nil {
+		return result, fmt.Errorf("Failed to unmarshal XML: %w", err)
+	}
+	return result, nil
+}
+
+// s3Backend S3 兼容接口的 XML 列表，支持 v1（marker）和 v2（continuation-token）翻页
+type s3Backend struct{}
+
+func isS3URL(location string) bool {
+	switch strings.ToLower(strings.SplitN(location, "://", 2)[0]) {
+	case "http", "https", "s3", "s3a", "oss", "cos", "gs":
+		return strings.Contains(location, "://")
+	}
+	return false
+}
+
+func (s3Backend) Name() string {
+	return "s3"
+}
+
+func (s3Backend) ListPage(ctx context.Context, location string) (*ListBucketResult, error) {
+	body, err := fetchListing(ctx, location)
+	if err != nil {
+		return nil, err
+	}
+	result, err := parseListing(body)
+	if result == nil {
+		return nil, err
+	}
+	if _, linkErr := result.MergeUrlAndFillLinks(location); linkErr != nil {
+		Logger().Warn(T("failed to fill links"), "url", location, "err", linkErr)
+	}
+	return result, err
+}
+
+func (s3Backend) NextPage(location string, page *ListBucketResult) (string, error) {
+	if !page.IsTruncated {
+		return "", nil
+	}
+	return tryGetNextPageURL(location, *page)
+}
+
+func (s3Backend) ObjectURL(location string, key string) string {
+	base, err := ParseBucketURL(location, "")
+	if err != nil {
+		return ""
+	}
+	return base.ObjectURL(key)
+}
+
+func (s3Backend) Capabilities() Capabilities {
+	return Capabilities{Paging: true, Download: true, Range: true}
+}
+
+// fileBackend 保存在本地的 XML/HTML 列表（浏览器里另存的页面），只有一页，没有下载链接
+type fileBackend struct{}
+
+func isLocalFile(location string) bool {
+	if strings.HasPrefix(location, "file://") {
+		return true
+	}
+	if strings.Contains(location, "://") {
+		return false
+	}
+	info, err := os.Stat(location)
+	return err == nil && !info.IsDir()
+}
+
+func (fileBackend) Name() string {
+	return "file"
+}
+
+func (fileBackend) ListPage(ctx context.Context, location string) (*ListBucketResult, error) {
+	fileText, err := os.ReadFile(strings.TrimPrefix(location, "file://"))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read XML file: %w", err)
+	}
+	result, err := parseListing(fileText)
+	if err != nil {
+		return nil, err
+	}
+	result.Url = location
+	return result, nil
+}
+
+func (fileBackend) NextPage(location string, page *ListBucketResult) (string, error) {
+	return "", nil
+}
+
+func (fileBackend) ObjectURL(location string, key string) string {
+	return ""
+}
+
+func (fileBackend) Capabilities() Capabilities {
+	return Capabilities{}
+}
@@ -0,0 +1,75 @@
+package s3viewer
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+// memBackend mem://a,b|c 表示两页：a、b 和 c
+type memBackend struct{}
+
+func (memBackend) Name() string { return "mem" }
+
+func (memBackend) ListPage(ctx context.Context, location string) (*ListBucketResult, error) {
+	pages := strings.Split(strings.TrimPrefix(location, "mem://"), "|")
+	result := &ListBucketResult{Url: location, IsTruncated: len(pages) > 1}
+	for _, key := range strings.Split(pages[0], ",") {
+		result.Files = append(result.Files, File{Key: key, Link: "mem:///" + key})
+	}
+	return result, nil
+}
+
+func (memBackend) NextPage(location string, page *ListBucketResult) (string, error) {
+	_, rest, ok := strings.Cut(location, "|")
+	if !ok {
+		return "", nil
+	}
+	return "mem://" + rest, nil
+}
+
+func (memBackend) ObjectURL(location string, key string) string { return "mem:///" + key }
+
+func (memBackend) Capabilities() Capabilities { return Capabilities{Paging: true} }
+
+func TestBackendRegistry(t *testing.T) {
+	RegisterBackend(memBackend{}, func(location string) bool { return strings.HasPrefix(location, "mem://") })
+
+	for location, name := range map[string]string{
+		"http://s3.example.com/": "s3",
+		"oss://acme/":            "s3",
+		"../test/h1.xml":         "file",
+		"file://../test/h1.xml":  "file",
+		"mem://a":                "mem",
+	} {
+		backend, err := BackendFor(location)
+		if assert.NoError(t, err, location) {
+			assert.Equal(t, name, backend.Name(), location)
+		}
+	}
+	_, err := BackendFor("ftp://example.com/")
+	assert.Error(t, err)
+	_, ok := LookupBackend("s3")
+	assert.True(t, ok)
+	assert.Contains(t, BackendNames(), "mem")
+
+	// 新的来源不需要改 Lister 和 LoadContext
+	result, err := LoadContext(context.Background(), "mem://a,b|c", 10)
+	assert.NoError(t, err)
+	assert.Len(t, result.Files, 3)
+	result, err = LoadContext(context.Background(), "mem://a,b|c", 1)
+	assert.NoError(t, err)
+	assert.Len(t, result.Files, 2)
+
+	// 本地文件只有一页，原样保留路径
+	result, err = LoadContext(context.Background(), "../test/h1.xml", 5)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, result.Files)
+	location, err := NormalizeLocation("../test/h1.xml", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "../test/h1.xml", location)
+	location, err = NormalizeLocation("acme.s3.amazonaws.com", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "http://acme.s3.amazonaws.com/", location)
+}
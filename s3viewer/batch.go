@@ -0,0 +1,345 @@
+package s3viewer
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// 按域名后缀识别云厂商
+var providerHosts = []struct {
+	suffix   string
+	provider string
+}{
+	{"amazonaws.com", "aws"},
+	{"amazonaws.com.cn", "aws"},
+	{"aliyuncs.com", "aliyun"},
+	{"myqcloud.com", "tencent"},
+	{"storage.googleapis.com", "gcp"},
+	{"blob.core.windows.net", "azure"},
+	{"myhuaweicloud.com", "huawei"},
+	{"ksyuncs.com", "kingsoft"},
+	{"bcebos.com", "baidu"},
+	{"digitaloceanspaces.com", "digitalocean"},
+	{"r2.cloudflarestorage.com", "cloudflare"},
+}
+
+// 域名识别不出来时（自定义域名、CDN），看响应头
+var providerHeaders = []struct {
+	header   string
+	provider string
+}{
+	{"X-Oss-Request-Id", "aliyun"},
+	{"X-Cos-Request-Id", "tencent"},
+	{"X-Obs-Request-Id", "huawei"},
+	{"X-Goog-Generation", "gcp"},
+	{"X-Guploader-Uploadid", "gcp"},
+	{"X-Ms-Request-Id", "azure"},
+	{"X-Amz-Request-Id", "aws"},
+}
+
+// DetectProvider 根据域名和响应头判断云厂商，识别不出来时返回空字符串；Server: MinIO 识别为 minio
+func DetectProvider(host string, header http.Header) string {
+	host = strings.ToLower(host)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	for _, p := range providerHosts {
+		if host == p.suffix || strings.HasSuffix(host, "."+p.suffix) {
+			return p.provider
+		}
+	}
+	if strings.Contains(strings.ToLower(header.Get("Server")), "minio") {
+		return "minio"
+	}
+	for _, p := range providerHeaders {
+		if header.Get(p.header) != "" {
+			return p.provider
+		}
+	}
+	return ""
+}
+
+// ReadTargets 按行读取目标 URL，忽略空行和 # 开头的注释，去重；没有协议时补上 http://
+func ReadTargets(r io.Reader) ([]string, error) {
+	var targets []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !strings.Contains(line, "://") {
+			line = "http://" + line
+		}
+		if !seen[line] {
+			seen[line] = true
+			targets = append(targets, line)
+		}
+	}
+	return targets, scanner.Err()
+}
+
+// BatchResult 单个目标的结果
+type BatchResult struct {
+	Url       string
+	Provider  string
+	Listable  bool
+	Objects   int
+	TotalSize int64
+	Sensitive int    // 分类等级为 high 及以上的对象个数，没有设置 Classifier 时为 0
+	Output    string `json:",omitempty"`
+	Err       string `json:",omitempty"`
+}
+
+// Batch 并发爬取多个目标，单个目标失败不影响其他目标
+type Batch struct {
+	Workers    int         // 同时爬取的目标数，<=0 时为 8
+	HostRate   float64     // 同一个主机每秒最多发几个请求（列表的每一页、探测厂商的 HEAD 都算），<=0 时不限制
+	MaxPage    int         // 每个目标最多翻几页
+	OutDir     string      // 不为空时每个目标的结果保存在这个目录里
+	Format     string      // 每个目标的输出格式：csv 或 json，默认 csv
+	Filter     *Filter     // 可选，保存和统计之前先过滤
+	Classifier *Classifier // 可选，统计敏感文件
+	Client     *http.Client
+
+	mu       sync.Mutex
+	limiters map[string]*rateLimiter
+}
+
+// Run 爬取所有目标，结果和 targets 一一对应
+func (b *Batch) Run(targets []string) []BatchResult {
+	return b.RunContext(context.Background(), targets)
+}
+
+// RunContext 同 Run；ctx 取消后不再开始新的目标，正在爬取的目标保留已经拉取到的部分，
+// 没来得及爬取的目标 Err 为 ctx.Err()
+func (b *Batch) RunContext(ctx context.Context, targets []string) []BatchResult {
+	workers := b.Workers
+	if workers <= 0 {
+		workers = 8
+	}
+	if b.Client == nil {
+		b.Client = NewHTTPClient(30 * time.Second)
+	}
+	if b.OutDir != "" {
+		if err := os.MkdirAll(b.OutDir, 0755); err != nil {
+			Logger().Error(T("failed to create output directory"), "dir", b.OutDir, "err", err)
+		}
+	}
+
+	results := make([]BatchResult, len(targets))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = b.runOne(ctx, targets[i])
+				if results[i].Err != "" {
+					Logger().Warn(T("failed to crawl"), "url", targets[i], "err", results[i].Err)
+				}
+			}
+		}()
+	}
+	fed := feedJobs(ctx, jobs, len(targets))
+	wg.Wait()
+	for i := fed; i < len(targets); i++ {
+		results[i] = BatchResult{Url: targets[i], Err: ctx.Err().Error()}
+	}
+	return results
+}
+
+func (b *Batch) runOne(ctx context.Context, target string) BatchResult {
+	result := BatchResult{Url: target}
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		result.Err = fmt.Sprintf("invalid URL: %v", target)
+		return result
+	}
+	result.Provider = DetectProvider(u.Host, nil)
+	backend := limitedBackend{Backend: s3Backend{}, limiter: b.hostLimiter(u.Host)}
+	listing, err := loadAll(ctx, NewBackendLister(backend, target, b.MaxPage))
+	if result.Provider == "" && ctx.Err() == nil {
+		result.Provider = b.probeProvider(ctx, target, u.Host)
+	}
+	if listing == nil {
+		result.Err = err.Error()
+		return result
+	}
+	result.Listable = true
+	if err != nil {
+		// 中途取消，保留已经拉取到的部分
+		result.Err = err.Error()
+	}
+
+	if b.Filter != nil {
+		if listing, err = FilterResult(listing, b.Filter); err != nil {
+			result.Err = err.Error()
+			return result
+		}
+	}
+	if b.Classifier != nil {
+		b.Classifier.ClassifyFiles(listing.Files)
+	}
+	result.Objects = len(listing.Files)
+	for _, f := range listing.Files {
+		result.TotalSize += int64(f.Size)
+		if severityRank[f.Severity] >= severityRank[SeverityHigh] {
+			result.Sensitive++
+		}
+	}
+
+	if b.OutDir != "" {
+		result.Output = filepath.Join(b.OutDir, TargetFileName(target)+"."+b.format())
+		if err := SaveResult(listing, result.Output); err != nil {
+			result.Err = err.Error()
+		}
+	}
+	return result
+}
+
+func (b *Batch) format() string {
+	if strings.EqualFold(b.Format, "json") {
+		return "json"
+	}
+	return "csv"
+}
+
+// hostLimiter 每个主机一个令牌桶
+func (b *Batch) hostLimiter(host string) *rateLimiter {
+	if b.HostRate <= 0 {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.limiters == nil {
+		b.limiters = make(map[string]*rateLimiter)
+	}
+	l, ok := b.limiters[host]
+	if !ok {
+		l = &rateLimiter{rate: b.HostRate, tokens: b.HostRate, last: time.Now()}
+		b.limiters[host] = l
+	}
+	return l
+}
+
+// limitedBackend 每拉取一页之前先从主机的令牌桶里取一个令牌，翻页也受 HostRate 限制
+type limitedBackend struct {
+	Backend
+	limiter *rateLimiter
+}
+
+func (b limitedBackend) ListPage(ctx context.Context, location string) (*ListBucketResult, error) {
+	if err := b.limiter.wait(ctx, 1); err != nil {
+		return nil, err
+	}
+	return b.Backend.ListPage(ctx, location)
+}
+
+// probeProvider 域名识别不出来时，HEAD 一下看响应头
+func (b *Batch) probeProvider(ctx context.Context, target string, host string) string {
+	if err := b.hostLimiter(host).wait(ctx, 1); err != nil {
+		return ""
+	}
+	req, err := NewRequestContext(ctx, "HEAD", target)
+	if err != nil {
+		return ""
+	}
+	resp, err := b.Client.Do(req)
+	if err != nil {
+		return ""
+	}
+	resp.Body.Close()
+	return DetectProvider(host, resp.Header)
+}
+
+var unsafeFileNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// TargetFileName 把目标 URL 转换成可以当文件名的字符串，例如 bucket.s3.amazonaws.com_backup_1a2b3c4d。
+// 协议被去掉、特殊字符都换成了 _，不同的目标可能得到同一个名字，所以最后加上完整 URL 的哈希前 8 位
+func TargetFileName(target string) string {
+	u, err := url.Parse(target)
+	name := target
+	if err == nil && u.Host != "" {
+		name = u.Host + u.Path
+		if u.RawQuery != "" {
+			name += "?" + u.RawQuery
+		}
+	}
+	name = strings.Trim(unsafeFileNameChars.ReplaceAllString(name, "_"), "_.")
+	if name == "" {
+		name = "target"
+	}
+	sum := sha1.Sum([]byte(target))
+	return name + "_" + hex.EncodeToString(sum[:4])
+}
+
+// PrintBatchSummary 以表格形式打印每个目标的汇总，最后一行是合计
+func PrintBatchSummary(w io.Writer, results []BatchResult) error {
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "Url\tProvider\tListable\tObjects\tTotalSize\tSensitive\tError")
+	var listable, objects, sensitive int
+	var totalSize int64
+	for _, r := range results {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%d\t%s\t%d\t%s\n", r.Url, r.Provider, formatBool(r.Listable), r.Objects, HumanSize(r.TotalSize), r.Sensitive, r.Err)
+		if r.Listable {
+			listable++
+		}
+		objects += r.Objects
+		totalSize += r.TotalSize
+		sensitive += r.Sensitive
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\ntargets=%d listable=%d objects=%d size=%s sensitive=%d\n", len(results), listable, objects, HumanSize(totalSize), sensitive)
+	return err
+}
+
+// SaveBatchSummary 保存汇总，.json 保存为 JSON，其余保存为 CSV
+func SaveBatchSummary(results []BatchResult, filePath string) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("Failed to create summary file: %w", err)
+	}
+	defer file.Close()
+
+	if strings.EqualFold(filepath.Ext(filePath), ".json") {
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(results)
+	}
+
+	writer := csv.NewWriter(file)
+	if err := writer.Write([]string{"Url", "Provider", "Listable", "Objects", "TotalSize", "Sensitive", "Output", "Error"}); err != nil {
+		return fmt.Errorf("Failed to write CSV headers: %w", err)
+	}
+	for _, r := range results {
+		record := []string{r.Url, r.Provider, formatBool(r.Listable), strconv.Itoa(r.Objects),
+			strconv.FormatInt(r.TotalSize, 10), strconv.Itoa(r.Sensitive), r.Output, r.Err}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("Failed to write CSV record: %w", err)
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
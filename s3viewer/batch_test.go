@@ -0,0 +1,108 @@
+package s3viewer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadTargets(t *testing.T) {
+	input := "# from asset discovery\nhttps://a.s3.amazonaws.com/\n\nb.oss-cn-hangzhou.aliyuncs.com\nhttps://a.s3.amazonaws.com/\n"
+	targets, err := ReadTargets(strings.NewReader(input))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://a.s3.amazonaws.com/", "http://b.oss-cn-hangzhou.aliyuncs.com"}, targets)
+}
+
+func TestDetectProvider(t *testing.T) {
+	assert.Equal(t, "aws", DetectProvider("bucket.s3.ap-east-1.amazonaws.com", nil))
+	assert.Equal(t, "aliyun", DetectProvider("bucket.oss-cn-hangzhou.aliyuncs.com:443", nil))
+	assert.Equal(t, "tencent", DetectProvider("bucket-1250000000.cos.ap-guangzhou.myqcloud.com", nil))
+	assert.Equal(t, "", DetectProvider("static.example.com", nil))
+	assert.Equal(t, "aliyun", DetectProvider("static.example.com", http.Header{"X-Oss-Request-Id": {"1"}}))
+	assert.Equal(t, "minio", DetectProvider("127.0.0.1:9000", http.Header{"Server": {"MinIO"}, "X-Amz-Request-Id": {"1"}}))
+}
+
+func TestBatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Oss-Request-Id", "5F0000")
+		switch r.URL.Path {
+		case "/open/":
+			fmt.Fprint(w, bucketXML(map[string]string{"db.sql": "aaaa", "index.html": "bbbbbbbb"}))
+		default:
+			http.Error(w, "<Error><Code>AccessDenied</Code></Error>", http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	classifier, _ := NewClassifier(nil)
+	outDir := t.TempDir()
+	batch := &Batch{Workers: 2, HostRate: 100, MaxPage: 1, OutDir: outDir, Classifier: classifier}
+	targets := []string{server.URL + "/open/", server.URL + "/closed/", "http://"}
+	results := batch.Run(targets)
+
+	assert.Len(t, results, 3)
+	assert.True(t, results[0].Listable)
+	assert.Equal(t, 2, results[0].Objects)
+	assert.Equal(t, int64(12), results[0].TotalSize)
+	assert.Equal(t, 1, results[0].Sensitive)
+	assert.Equal(t, "aliyun", results[0].Provider)
+	assert.FileExists(t, results[0].Output)
+
+	// 失败的目标不影响其他目标
+	assert.False(t, results[1].Listable)
+	assert.Contains(t, results[1].Err, "403")
+	assert.Empty(t, results[1].Output)
+	assert.NotEmpty(t, results[2].Err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, PrintBatchSummary(&buf, results))
+	assert.Contains(t, buf.String(), "targets=3 listable=1 objects=2")
+
+	summaryPath := filepath.Join(outDir, "summary.csv")
+	assert.NoError(t, SaveBatchSummary(results, summaryPath))
+	data, _ := os.ReadFile(summaryPath)
+	assert.Equal(t, 4, strings.Count(string(data), "\n"))
+}
+
+func TestBatchHostRate(t *testing.T) {
+	server := pagedServer([][]string{{"a"}, {"b"}, {"c"}, {"d"}})
+	defer server.Close()
+
+	// 4 页列表加 1 个探测厂商的 HEAD，每秒 2 个请求：前 2 个不用等，后 3 个共等 1.5 秒
+	start := time.Now()
+	results := (&Batch{HostRate: 2, MaxPage: 10}).Run([]string{server.URL + "/"})
+	assert.Equal(t, 4, results[0].Objects)
+	assert.GreaterOrEqual(t, time.Since(start), time.Second)
+}
+
+func TestBatchHostRateCancel(t *testing.T) {
+	server := pagedServer([][]string{{"a"}, {"b"}, {"c"}, {"d"}})
+	defer server.Close()
+
+	// 每 10 秒一个请求，取消后不再等令牌
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	results := (&Batch{HostRate: 0.1, MaxPage: 10}).RunContext(ctx, []string{server.URL + "/"})
+	assert.Less(t, time.Since(start), 2*time.Second)
+	assert.Contains(t, results[0].Err, context.DeadlineExceeded.Error())
+}
+
+func TestTargetFileName(t *testing.T) {
+	assert.Regexp(t, `^bucket\.s3\.amazonaws\.com_[0-9a-f]{8}$`, TargetFileName("https://bucket.s3.amazonaws.com/"))
+	assert.Regexp(t, `^s3\.amazonaws\.com_bucket_backup_prefix_a_b_[0-9a-f]{8}$`, TargetFileName("http://s3.amazonaws.com/bucket/backup/?prefix=a/b"))
+	assert.Regexp(t, `^127\.0\.0\.1_8080_[0-9a-f]{8}$`, TargetFileName("http://127.0.0.1:8080"))
+
+	// 去掉协议、替换特殊字符后一样的目标，文件名不能一样
+	assert.NotEqual(t, TargetFileName("http://h/a"), TargetFileName("https://h/a"))
+	assert.NotEqual(t, TargetFileName("http://h/a/b_c"), TargetFileName("http://h/a/b/c"))
+	assert.Equal(t, TargetFileName("http://h/a"), TargetFileName("http://h/a"))
+}
@@ -0,0 +1,193 @@
+package s3viewer
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// BucketURL 一个 bucket（或 bucket 里的某个前缀）的地址，列目录的 URL 和对象的基础 URL 分开保存
+type BucketURL struct {
+	Scheme   string     // http 或 https
+	Host     string     // 带端口
+	BasePath string     // bucket 根目录的路径，以 / 开头和结尾：虚拟主机风格为 /，路径风格为 /bucket/
+	Bucket   string     // 识别不出来时（自定义域名、CDN）为空
+	Provider string     // 见 DetectProvider
+	Query    url.Values // 列目录时带上的参数，prefix、delimiter、list-type 等
+}
+
+// 路径风格的 endpoint，第一段路径是 bucket 名
+var pathStyleHosts = regexp.MustCompile(`^(?:s3(?:[.-](?:dualstack\.)?[a-z0-9-]+)?\.amazonaws\.com(?:\.cn)?|storage\.googleapis\.com|storage\.cloud\.google\.com)$`)
+
+// 虚拟主机风格的 endpoint，第一段域名是 bucket 名
+var virtualHostedHosts = regexp.MustCompile(`^([a-z0-9][a-z0-9.-]*?)\.(?:s3(?:-accelerate|-website)?(?:[.-](?:dualstack\.)?[a-z0-9-]+)?\.amazonaws\.com(?:\.cn)?|oss(?:-[a-z0-9-]+)?\.aliyuncs\.com|cos(?:-website)?\.[a-z0-9-]+\.myqcloud\.com|storage\.googleapis\.com|obs\.[a-z0-9-]+\.myhuaweicloud\.com)$`)
+
+// ParseBucketURL 解析 -u 的各种写法：
+//
+//	s3://bucket/prefix、oss://bucket/prefix、cos://bucket/prefix、gs://bucket/prefix
+//	https://s3.eu-west-1.amazonaws.com/bucket/prefix/（路径风格）
+//	https://bucket.oss-cn-hangzhou.aliyuncs.com/prefix/（虚拟主机风格）
+//	http://1.2.3.4:9000/bucket/（识别不出来的主机，路径原样保留）
+//
+// endpoint 不为空时覆盖默认的 endpoint，例如 http://127.0.0.1:9000（MinIO，路径风格）或 oss-cn-shanghai.aliyuncs.com
+func ParseBucketURL(raw string, endpoint string) (*BucketURL, error) {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "s3", "s3a", "oss", "cos", "gs":
+		return parseSchemeURL(u, endpoint)
+	case "http", "https":
+	default:
+		return nil, fmt.Errorf("unsupported URL scheme: %v", raw)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid URL, host is missing: %v", raw)
+	}
+
+	b := &BucketURL{Scheme: strings.ToLower(u.Scheme), Host: u.Host, BasePath: "/", Query: u.Query()}
+	host := strings.ToLower(u.Hostname())
+	b.Provider = DetectProvider(host, nil)
+	segments := strings.SplitN(strings.TrimPrefix(u.Path, "/"), "/", 2)
+
+	var prefix string
+	switch {
+	case pathStyleHosts.MatchString(host) || endpoint != "" && sameHost(u, endpoint):
+		if segments[0] != "" {
+			b.Bucket = segments[0]
+			b.BasePath = "/" + segments[0] + "/"
+			if len(segments) == 2 {
+				prefix = segments[1]
+			}
+		}
+	case virtualHostedHosts.MatchString(host):
+		b.Bucket = virtualHostedHosts.FindStringSubmatch(host)[1]
+		prefix = strings.TrimPrefix(u.Path, "/")
+	default:
+		// 自定义域名、CDN、MinIO 等：不知道路径里哪部分是 bucket，按原来的方式把路径当作根目录
+		b.BasePath = u.Path
+		if !strings.HasSuffix(b.BasePath, "/") {
+			b.BasePath += "/"
+		}
+	}
+	if prefix != "" && b.Query.Get("prefix") == "" {
+		b.Query.Set("prefix", prefix)
+	}
+	return b, nil
+}
+
+// parseSchemeURL 解析 s3://、oss://、cos://、gs://
+func parseSchemeURL(u *url.URL, endpoint string) (*BucketURL, error) {
+	scheme := strings.ToLower(u.Scheme)
+	bucket := u.Host
+	if bucket == "" {
+		return nil, fmt.Errorf("invalid URL, bucket is missing: %v", u)
+	}
+	b := &BucketURL{Scheme: "https", Bucket: bucket, BasePath: "/", Query: url.Values{}}
+	if prefix := strings.TrimPrefix(u.Path, "/"); prefix != "" {
+		b.Query.Set("prefix", prefix)
+	}
+
+	if endpoint == "" {
+		switch scheme {
+		case "s3", "s3a":
+			endpoint = "s3.amazonaws.com"
+		case "oss":
+			endpoint = "oss.aliyuncs.com"
+		case "gs":
+			endpoint = "storage.googleapis.com"
+		case "cos":
+			return nil, fmt.Errorf("cos:// needs an endpoint, e.g. cos.ap-guangzhou.myqcloud.com")
+		}
+	}
+	e, err := parseEndpoint(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	b.Scheme = e.Scheme
+	b.Provider = DetectProvider(e.Hostname(), nil)
+
+	// 已知的云厂商用虚拟主机风格；自定义 endpoint（MinIO 等）、GCS 和带点的 bucket 名用路径风格
+	if b.Provider == "" || b.Provider == "gcp" || strings.Contains(bucket, ".") && b.Provider == "aws" {
+		b.Host = e.Host
+		b.BasePath = "/" + bucket + "/"
+	} else {
+		b.Host = bucket + "." + e.Host
+	}
+	return b, nil
+}
+
+// parseEndpoint endpoint 可以不带协议，默认 https
+func parseEndpoint(endpoint string) (*url.URL, error) {
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	e, err := url.Parse(endpoint)
+	if err != nil || e.Host == "" {
+		return nil, fmt.Errorf("invalid endpoint: %v", endpoint)
+	}
+	return e, nil
+}
+
+func sameHost(u *url.URL, endpoint string) bool {
+	e, err := parseEndpoint(endpoint)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, e.Host) || strings.EqualFold(u.Hostname(), e.Host)
+}
+
+// Prefix 列目录的前缀
+func (b *BucketURL) Prefix() string {
+	return b.Query.Get("prefix")
+}
+
+// BaseURL 对象的基础 URL，不带查询参数，以 / 结尾
+func (b *BucketURL) BaseURL() string {
+	return b.Scheme + "://" + b.Host + escapeKey(b.BasePath)
+}
+
+// ListingURL 列目录的 URL，带上 prefix 等参数
+func (b *BucketURL) ListingURL() string {
+	listing := b.BaseURL()
+	if len(b.Query) > 0 {
+		listing += "?" + b.Query.Encode()
+	}
+	return listing
+}
+
+// ObjectURL 对象的下载链接，key 按路径逐段转义
+func (b *BucketURL) ObjectURL(key string) string {
+	return b.BaseURL() + escapeKey(key)
+}
+
+// String 同 ListingURL
+func (b *BucketURL) String() string {
+	return b.ListingURL()
+}
+
+// escapeKey 按 / 分段转义；+ 也要转义，否则 S3 会当成空格
+func escapeKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, s := range segments {
+		segments[i] = strings.ReplaceAll(url.PathEscape(s), "+", "%2B")
+	}
+	return strings.Join(segments, "/")
+}
+
+// NormalizeURL 把 -u 的各种写法转换成可以直接请求的列目录 URL
+func NormalizeURL(raw string, endpoint string) (string, error) {
+	if raw != "" && !strings.Contains(raw, "://") {
+		// 只写了主机名，例如 bucket.s3.amazonaws.com
+		raw = "http://" + raw
+	}
+	b, err := ParseBucketURL(raw, endpoint)
+	if err != nil {
+		return "", err
+	}
+	return b.ListingURL(), nil
+}
@@ -0,0 +1,90 @@
+package s3viewer
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestParseBucketURL(t *testing.T) {
+	for _, c := range []struct {
+		raw      string
+		endpoint string
+		bucket   string
+		listing  string
+		object   string // key 为 "a b/c+d.txt" 时的链接
+	}{
+		{"s3://acme/backup/", "", "acme",
+			"https://acme.s3.amazonaws.com/?prefix=backup%2F", "https://acme.s3.amazonaws.com/a%20b/c%2Bd.txt"},
+		{"s3://acme.logs", "", "acme.logs",
+			"https://s3.amazonaws.com/acme.logs/", "https://s3.amazonaws.com/acme.logs/a%20b/c%2Bd.txt"},
+		{"oss://acme/img", "oss-cn-shanghai.aliyuncs.com", "acme",
+			"https://acme.oss-cn-shanghai.aliyuncs.com/?prefix=img", "https://acme.oss-cn-shanghai.aliyuncs.com/a%20b/c%2Bd.txt"},
+		{"cos://acme-1250000000", "cos.ap-guangzhou.myqcloud.com", "acme-1250000000",
+			"https://acme-1250000000.cos.ap-guangzhou.myqcloud.com/", "https://acme-1250000000.cos.ap-guangzhou.myqcloud.com/a%20b/c%2Bd.txt"},
+		{"s3://acme/", "http://127.0.0.1:9000", "acme",
+			"http://127.0.0.1:9000/acme/", "http://127.0.0.1:9000/acme/a%20b/c%2Bd.txt"},
+		{"https://s3.eu-west-1.amazonaws.com/acme/backup/", "", "acme",
+			"https://s3.eu-west-1.amazonaws.com/acme/?prefix=backup%2F", "https://s3.eu-west-1.amazonaws.com/acme/a%20b/c%2Bd.txt"},
+		{"https://acme.oss-cn-hangzhou.aliyuncs.com/?prefix=x&marker=y", "", "acme",
+			"https://acme.oss-cn-hangzhou.aliyuncs.com/?marker=y&prefix=x", "https://acme.oss-cn-hangzhou.aliyuncs.com/a%20b/c%2Bd.txt"},
+		{"http://127.0.0.1:9000/acme/backup/", "127.0.0.1:9000", "acme",
+			"http://127.0.0.1:9000/acme/?prefix=backup%2F", "http://127.0.0.1:9000/acme/a%20b/c%2Bd.txt"},
+		// 识别不出来的主机，路径原样当作根目录
+		{"http://cdn.example.com/files?list-type=2", "", "",
+			"http://cdn.example.com/files/?list-type=2", "http://cdn.example.com/files/a%20b/c%2Bd.txt"},
+	} {
+		b, err := ParseBucketURL(c.raw, c.endpoint)
+		if !assert.NoError(t, err, c.raw) {
+			continue
+		}
+		assert.Equal(t, c.bucket, b.Bucket, c.raw)
+		assert.Equal(t, c.listing, b.ListingURL(), c.raw)
+		assert.Equal(t, c.object, b.ObjectURL("a b/c+d.txt"), c.raw)
+	}
+
+	_, err := ParseBucketURL("cos://acme-1250000000", "")
+	assert.Error(t, err)
+	_, err = ParseBucketURL("ftp://acme", "")
+	assert.Error(t, err)
+
+	listing, err := NormalizeURL("acme.s3.amazonaws.com", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "http://acme.s3.amazonaws.com/", listing)
+}
+
+func TestMergeUrlAndFillLinks(t *testing.T) {
+	result := &ListBucketResult{Files: []File{{Key: "报告/2024 Q1#1.pdf"}, {Key: "100%.txt"}}}
+	result.MergeUrlAndFillLinks("http://s3.example.com/?prefix=%E6%8A%A5&marker=abc")
+	assert.Equal(t, "http://s3.example.com/%E6%8A%A5%E5%91%8A/2024%20Q1%231.pdf", result.Files[0].Link)
+	assert.Equal(t, "http://s3.example.com/100%25.txt", result.Files[1].Link)
+}
+
+func TestLoadRemoteHTTPRecursiveKeepsPrefix(t *testing.T) {
+	var mu sync.Mutex
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		queries = append(queries, r.URL.RawQuery)
+		mu.Unlock()
+		if r.URL.Query().Get("prefix") != "backup/" {
+			http.Error(w, "prefix lost", http.StatusBadRequest)
+			return
+		}
+		key, truncated := "backup/a.sql", true
+		if r.URL.Query().Get("marker") != "" {
+			key, truncated = "backup/b.sql", false
+		}
+		fmt.Fprintf(w, `<ListBucketResult><IsTruncated>%v</IsTruncated><Contents><Key>%s</Key><Size>1</Size></Contents></ListBucketResult>`, truncated, key)
+	}))
+	defer server.Close()
+
+	result, err := LoadRemoteHTTPRecursive(server.URL+"/?prefix=backup/", 5)
+	assert.NoError(t, err)
+	assert.Len(t, result.Files, 2)
+	assert.Equal(t, []string{"prefix=backup/", "marker=backup%2Fa.sql&prefix=backup%2F"}, queries)
+	assert.Equal(t, server.URL+"/backup/b.sql", result.Files[1].Link)
+}
@@ -0,0 +1,270 @@
+package s3viewer
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// 敏感等级，从高到低
+const (
+	SeverityCritical = "critical"
+	SeverityHigh     = "high"
+	SeverityMedium   = "medium"
+	SeverityLow      = "low"
+	SeverityInfo     = "info"
+)
+
+var severityRank = map[string]int{
+	SeverityCritical: 5,
+	SeverityHigh:     4,
+	SeverityMedium:   3,
+	SeverityLow:      2,
+	SeverityInfo:     1,
+}
+
+// Rule 一条按 Key 分类的规则，Exts、Names、Keywords、Regex 命中任意一个即可
+type Rule struct {
+	Name     string   `yaml:"name"`
+	Category string   `yaml:"category"`
+	Severity string   `yaml:"severity"`
+	Exts     []string `yaml:"exts"`     // 扩展名，例如 sql、tar.gz
+	Names    []string `yaml:"names"`    // 文件名 glob，例如 id_rsa*、.env
+	Keywords []string `yaml:"keywords"` // Key 中包含的关键词，大小写不敏感
+	Regex    string   `yaml:"regex"`    // 对完整 Key 做正则匹配
+}
+
+// BuiltinRules 内置规则：备份、凭据、办公文档、数据库、日志、源码包、个人信息
+var BuiltinRules = []Rule{
+	{
+		Name: "private-key", Category: "credential", Severity: SeverityCritical,
+		Names: []string{"id_rsa*", "id_dsa*", "id_ecdsa*", "id_ed25519*", "*.pem", "*.key", "*.p12", "*.pfx", "*.jks", "*.keystore", "*.ppk"},
+	},
+	{
+		Name: "credential-file", Category: "credential", Severity: SeverityCritical,
+		Names: []string{".env", ".env.*", "*.env", "credentials", "credentials.*", ".htpasswd", ".git-credentials", ".npmrc", ".pgpass", ".netrc", "*.kdbx", "wp-config.php"},
+	},
+	{
+		Name: "credential-keyword", Category: "credential", Severity: SeverityHigh,
+		Keywords: []string{"password", "passwd", "secret", "accesskey", "access_key", "密码", "密钥", "账号"},
+	},
+	{
+		Name: "vcs-metadata", Category: "source", Severity: SeverityHigh,
+		Regex: `(?i)(^|/)\.(git|svn|hg)/`,
+	},
+	{
+		Name: "database-dump", Category: "database", Severity: SeverityHigh,
+		Exts: []string{"sql", "sql.gz", "dump", "dmp", "mdb", "accdb", "sqlite", "sqlite3", "db", "frm", "ibd", "bson", "rdb"},
+	},
+	{
+		Name: "backup", Category: "backup", Severity: SeverityHigh,
+		Exts:     []string{"bak", "backup", "old", "orig", "swp", "tar", "tar.gz", "tgz", "tar.bz2", "tar.xz"},
+		Keywords: []string{"backup", "备份"},
+	},
+	{
+		Name: "pii", Category: "pii", Severity: SeverityHigh,
+		Keywords: []string{
+			"身份证", "合同", "名单", "工资", "薪资", "简历", "户口", "护照", "银行卡", "通讯录", "花名册", "社保", "体检",
+			"idcard", "id_card", "passport", "salary", "payroll", "resume", "contract",
+		},
+	},
+	{
+		Name: "source-archive", Category: "source", Severity: SeverityMedium,
+		Regex: `(?i)(src|source|code|源码|代码)[^/]*\.(zip|rar|7z|tar|tar\.gz|tgz|war|jar)$`,
+	},
+	{
+		Name: "log", Category: "log", Severity: SeverityMedium,
+		Exts:     []string{"log", "log.gz", "out"},
+		Keywords: []string{"/logs/", "/log/", "日志"},
+	},
+	{
+		Name: "office-document", Category: "office", Severity: SeverityLow,
+		Exts: []string{"doc", "docx", "xls", "xlsx", "ppt", "pptx", "pdf", "csv", "wps", "et", "dps", "odt", "ods", "rtf"},
+	},
+	{
+		Name: "archive", Category: "archive", Severity: SeverityLow,
+		Exts: []string{"zip", "rar", "7z", "gz", "bz2", "xz"},
+	},
+}
+
+// RuleFile 规则文件（YAML）的格式
+type RuleFile struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// LoadRules 从 YAML 文件读取自定义规则
+func LoadRules(filePath string) ([]Rule, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read rules file: %w", err)
+	}
+	var ruleFile RuleFile
+	if err := yaml.Unmarshal(content, &ruleFile); err != nil {
+		return nil, fmt.Errorf("Failed to parse rules file: %w", err)
+	}
+	return ruleFile.Rules, nil
+}
+
+type compiledRule struct {
+	Rule
+	exts     []string
+	names    []*regexp.Regexp
+	keywords []string
+	regex    *regexp.Regexp
+}
+
+// Classifier 按规则给文件分类
+type Classifier struct {
+	rules []compiledRule
+}
+
+// NewClassifier 用内置规则加上 extra 创建 Classifier
+func NewClassifier(extra []Rule) (*Classifier, error) {
+	c := new(Classifier)
+	rules := make([]Rule, 0, len(extra)+len(BuiltinRules))
+	rules = append(append(rules, extra...), BuiltinRules...)
+	for _, rule := range rules {
+		compiled, err := compileRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		c.rules = append(c.rules, compiled)
+	}
+	return c, nil
+}
+
+func compileRule(rule Rule) (compiledRule, error) {
+	if rule.Category == "" {
+		return compiledRule{}, fmt.Errorf("rule %q has no category", rule.Name)
+	}
+	rule.Severity = strings.ToLower(rule.Severity)
+	if rule.Severity == "" {
+		rule.Severity = SeverityMedium
+	}
+	if _, ok := severityRank[rule.Severity]; !ok {
+		return compiledRule{}, fmt.Errorf("rule %q has unknown severity: %v", rule.Name, rule.Severity)
+	}
+
+	c := compiledRule{Rule: rule}
+	for _, ext := range rule.Exts {
+		c.exts = append(c.exts, "."+strings.ToLower(strings.TrimPrefix(ext, ".")))
+	}
+	for _, name := range rule.Names {
+		re, err := globToRegexp(strings.ToLower(name))
+		if err != nil {
+			return compiledRule{}, fmt.Errorf("rule %q: %w", rule.Name, err)
+		}
+		c.names = append(c.names, re)
+	}
+	for _, keyword := range rule.Keywords {
+		c.keywords = append(c.keywords, strings.ToLower(keyword))
+	}
+	if rule.Regex != "" {
+		re, err := regexp.Compile(rule.Regex)
+		if err != nil {
+			return compiledRule{}, fmt.Errorf("rule %q: invalid regex: %w", rule.Name, err)
+		}
+		c.regex = re
+	}
+	return c, nil
+}
+
+func (r *compiledRule) matches(key string) bool {
+	lower := strings.ToLower(key)
+	base := path.Base(lower)
+	for _, ext := range r.exts {
+		if strings.HasSuffix(base, ext) {
+			return true
+		}
+	}
+	for _, name := range r.names {
+		if name.MatchString(base) {
+			return true
+		}
+	}
+	for _, keyword := range r.keywords {
+		if strings.Contains(lower, keyword) {
+			return true
+		}
+	}
+	return r.regex != nil && r.regex.MatchString(key)
+}
+
+// Classify 返回命中规则中等级最高的分类；等级相同时，自定义规则优先，其次按规则顺序
+func (c *Classifier) Classify(key string) (category string, severity string) {
+	if strings.HasSuffix(key, "/") {
+		return "", ""
+	}
+	best := -1
+	for i := range c.rules {
+		if !c.rules[i].matches(key) {
+			continue
+		}
+		if best < 0 || severityRank[c.rules[i].Severity] > severityRank[c.rules[best].Severity] {
+			best = i
+		}
+	}
+	if best < 0 {
+		return "", ""
+	}
+	return c.rules[best].Category, c.rules[best].Severity
+}
+
+// ClassifyFiles 给每个文件填上 Category 和 Severity
+func (c *Classifier) ClassifyFiles(files []File) {
+	for i := range files {
+		files[i].Category, files[i].Severity = c.Classify(files[i].Key)
+	}
+}
+
+// CategoryCount 分类汇总的一行
+type CategoryCount struct {
+	Category string
+	Severity string
+	Count    int
+	Size     int64
+}
+
+// SummarizeCategories 按分类和等级汇总，等级高的在前
+func SummarizeCategories(files []File) []CategoryCount {
+	index := make(map[[2]string]int)
+	var summary []CategoryCount
+	for _, file := range files {
+		if file.Category == "" {
+			continue
+		}
+		k := [2]string{file.Category, file.Severity}
+		i, ok := index[k]
+		if !ok {
+			i = len(summary)
+			index[k] = i
+			summary = append(summary, CategoryCount{Category: file.Category, Severity: file.Severity})
+		}
+		summary[i].Count++
+		summary[i].Size += int64(file.Size)
+	}
+	sort.SliceStable(summary, func(i, j int) bool {
+		ri, rj := severityRank[summary[i].Severity], severityRank[summary[j].Severity]
+		if ri != rj {
+			return ri > rj
+		}
+		return summary[i].Count > summary[j].Count
+	})
+	return summary
+}
+
+// PrintCategorySummary 打印分类汇总表
+func PrintCategorySummary(w io.Writer, files []File) error {
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "Severity\tCategory\tCount\tSize")
+	for _, row := range SummarizeCategories(files) {
+		fmt.Fprintf(writer, "%s\t%s\t%d\t%s\n", row.Severity, row.Category, row.Count, HumanSize(row.Size))
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,99 @@
+package s3viewer
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	c, err := NewClassifier(nil)
+	if err != nil {
+		t.Fatalf("Failed to create classifier: %v", err)
+	}
+
+	cases := map[string][2]string{
+		"db/backup_20240101.sql":     {"database", SeverityHigh},
+		"www/site.tar.gz":            {"backup", SeverityHigh},
+		"deploy/.env":                {"credential", SeverityCritical},
+		"home/ubuntu/.ssh/id_rsa":    {"credential", SeverityCritical},
+		"certs/server.pem":           {"credential", SeverityCritical},
+		"hr/2024年员工工资表.xlsx":         {"pii", SeverityHigh},
+		"hr/员工身份证复印件.pdf":            {"pii", SeverityHigh},
+		"nginx/logs/access.log":      {"log", SeverityMedium},
+		"release/project-source.zip": {"source", SeverityMedium},
+		"repo/.git/config":           {"source", SeverityHigh},
+		"docs/manual.docx":           {"office", SeverityLow},
+		"images/logo.png":            {"", ""},
+		"backup/":                    {"", ""},
+		"report/PASSWORD-list.txt":   {"credential", SeverityHigh},
+		"book/中华书局版历史七年级上册_5344_20201016.jlk": {"", ""},
+	}
+	for key, want := range cases {
+		category, severity := c.Classify(key)
+		assert.Equal(t, want, [2]string{category, severity}, key)
+	}
+}
+
+func TestClassifyCustomRules(t *testing.T) {
+	rulesFile := filepath.Join(t.TempDir(), "rules.yaml")
+	assert.NoError(t, os.WriteFile(rulesFile, []byte(`
+rules:
+  - name: customer-export
+    category: customer
+    severity: critical
+    keywords: ["客户"]
+  - name: jlk
+    category: ebook
+    severity: info
+    exts: [jlk]
+`), 0644))
+
+	rules, err := LoadRules(rulesFile)
+	if err != nil {
+		t.Fatalf("Failed to load rules: %v", err)
+	}
+	c, err := NewClassifier(rules)
+	if err != nil {
+		t.Fatalf("Failed to create classifier: %v", err)
+	}
+
+	category, severity := c.Classify("export/客户名单.xlsx")
+	assert.Equal(t, "customer", category)
+	assert.Equal(t, SeverityCritical, severity)
+
+	category, severity = c.Classify("book/a.jlk")
+	assert.Equal(t, "ebook", category)
+	assert.Equal(t, SeverityInfo, severity)
+
+	_, err = NewClassifier([]Rule{{Name: "bad", Category: "x", Severity: "urgent"}})
+	assert.Error(t, err)
+	_, err = NewClassifier([]Rule{{Name: "no-category"}})
+	assert.Error(t, err)
+}
+
+func TestCategorySummary(t *testing.T) {
+	files := []File{
+		{Key: "a.sql", Size: 100},
+		{Key: "b.sql", Size: 200},
+		{Key: ".env", Size: 10},
+		{Key: "c.docx", Size: 1},
+		{Key: "d.png", Size: 1},
+	}
+	c, _ := NewClassifier(nil)
+	c.ClassifyFiles(files)
+
+	summary := SummarizeCategories(files)
+	assert.Equal(t, []CategoryCount{
+		{Category: "credential", Severity: SeverityCritical, Count: 1, Size: 10},
+		{Category: "database", Severity: SeverityHigh, Count: 2, Size: 300},
+		{Category: "office", Severity: SeverityLow, Count: 1, Size: 1},
+	}, summary)
+
+	var buf bytes.Buffer
+	assert.NoError(t, PrintCategorySummary(&buf, files))
+	assert.Len(t, strings.Split(strings.TrimSpace(buf.String()), "\n"), 4)
+}
@@ -0,0 +1,153 @@
+package s3viewer
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Detector 在文本中查找一类敏感信息，Validate 用来排除误报（例如校验位不对的身份证号）
+type Detector struct {
+	Name     string
+	Pattern  *regexp.Regexp
+	Group    int // 取第几个分组作为匹配值，0 表示整个匹配
+	Validate func(string) bool
+	Redact   func(string) string
+}
+
+// Finding 某一类敏感信息的命中情况，Samples 是打码后的样例
+type Finding struct {
+	Type    string
+	Count   int
+	Samples []string
+}
+
+// maxSamples 每类最多保留几个样例
+const maxSamples = 3
+
+// DefaultDetectors 内置的检测器：云厂商密钥、私钥、JWT、身份证号、手机号、银行卡号、邮箱
+var DefaultDetectors = []Detector{
+	{Name: "aws_access_key", Pattern: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{
+		Name:    "aws_secret_key",
+		Pattern: regexp.MustCompile(`(?i)aws.{0,20}?(?:secret|sk).{0,20}?[:=]\s*["']?([A-Za-z0-9/+]{40})\b`),
+		Group:   1,
+	},
+	{Name: "aliyun_access_key", Pattern: regexp.MustCompile(`\bLTAI[0-9A-Za-z]{12,20}\b`)},
+	{Name: "tencent_secret_id", Pattern: regexp.MustCompile(`\bAKID[0-9A-Za-z]{32}\b`)},
+	{
+		Name:    "private_key",
+		Pattern: regexp.MustCompile(`-----BEGIN (?:RSA |EC |DSA |OPENSSH |ENCRYPTED |PGP )?PRIVATE KEY(?: BLOCK)?-----`),
+		Redact:  func(s string) string { return s },
+	},
+	{Name: "jwt", Pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`)},
+	{
+		Name:     "cn_id_card",
+		Pattern:  regexp.MustCompile(`\b[1-9]\d{5}(?:18|19|20)\d{2}(?:0[1-9]|1[0-2])(?:0[1-9]|[12]\d|3[01])\d{3}[\dXx]\b`),
+		Validate: ValidChineseIDCard,
+	},
+	{Name: "cn_phone", Pattern: regexp.MustCompile(`\b1[3-9]\d{9}\b`)},
+	{
+		Name:     "bank_card",
+		Pattern:  regexp.MustCompile(`\b(?:62|4\d|5[1-5]|3[47])\d{14,17}\b`),
+		Validate: func(s string) bool { return ValidLuhn(s) && !ValidChineseIDCard(s) },
+	},
+	{
+		Name:    "email",
+		Pattern: regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`),
+		Redact:  redactEmail,
+	},
+}
+
+// Detect 用 detectors 扫描文本，返回每类的命中次数和打码后的样例，按类型名排序
+func Detect(text string, detectors []Detector) []Finding {
+	var findings []Finding
+	for _, d := range detectors {
+		finding := Finding{Type: d.Name}
+		seen := make(map[string]bool)
+		for _, m := range d.Pattern.FindAllStringSubmatch(text, -1) {
+			value := m[d.Group]
+			if d.Validate != nil && !d.Validate(value) {
+				continue
+			}
+			finding.Count++
+			redact := d.Redact
+			if redact == nil {
+				redact = Redact
+			}
+			sample := redact(value)
+			if !seen[sample] && len(finding.Samples) < maxSamples {
+				seen[sample] = true
+				finding.Samples = append(finding.Samples, sample)
+			}
+		}
+		if finding.Count > 0 {
+			findings = append(findings, finding)
+		}
+	}
+	sort.Slice(findings, func(i, j int) bool { return findings[i].Type < findings[j].Type })
+	return findings
+}
+
+// Redact 保留首尾各 4 个字符（短字符串保留更少），中间打码
+func Redact(s string) string {
+	runes := []rune(s)
+	keep := 4
+	if len(runes) <= 12 {
+		keep = len(runes) / 4
+	}
+	return string(runes[:keep]) + strings.Repeat("*", len(runes)-2*keep) + string(runes[len(runes)-keep:])
+}
+
+func redactEmail(s string) string {
+	user, domain, _ := strings.Cut(s, "@")
+	if len(user) <= 1 {
+		return "*@" + domain
+	}
+	return user[:1] + strings.Repeat("*", len(user)-1) + "@" + domain
+}
+
+// ValidChineseIDCard 校验 18 位身份证号的校验位（GB 11643）
+func ValidChineseIDCard(s string) bool {
+	if len(s) != 18 {
+		return false
+	}
+	weights := []int{7, 9, 10, 5, 8, 4, 2, 1, 6, 3, 7, 9, 10, 5, 8, 4, 2}
+	sum := 0
+	for i := 0; i < 17; i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+		sum += int(s[i]-'0') * weights[i]
+	}
+	check := "10X98765432"[sum%11]
+	last := s[17]
+	if last == 'x' {
+		last = 'X'
+	}
+	return last == check
+}
+
+// ValidLuhn Luhn 校验，银行卡号用
+func ValidLuhn(s string) bool {
+	if len(s) < 12 {
+		return false
+	}
+	sum := 0
+	double := false
+	for i := len(s) - 1; i >= 0; i-- {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+		d := int(s[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
@@ -0,0 +1,287 @@
+package s3viewer
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Modification 同一个 Key 的大小、ETag 或修改时间变了
+type Modification struct {
+	Key     string
+	Old     File
+	New     File
+	Changes []string // 变化的字段：Size、ETag、LastModified
+}
+
+// Rename ETag 和大小相同、Key 不同，视为改名（或移动）
+type Rename struct {
+	From File
+	To   File
+}
+
+// DiffTotals 汇总
+type DiffTotals struct {
+	Old          int
+	New          int
+	Added        int
+	Removed      int
+	Modified     int
+	Renamed      int
+	Unchanged    int
+	AddedSize    int64
+	RemovedSize  int64
+	ModifiedSize int64 // 修改后的大小减去修改前的大小
+}
+
+// DiffResult 两次爬取结果的差异
+type DiffResult struct {
+	Added    []File
+	Removed  []File
+	Modified []Modification
+	Renamed  []Rename
+	Totals   DiffTotals
+}
+
+// Empty 没有任何变化
+func (d *DiffResult) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Modified) == 0 && len(d.Renamed) == 0
+}
+
+// Diff 比较两次爬取的结果，按 Key 对齐；结果里的各个列表都按 Key 排序
+func Diff(oldResult *ListBucketResult, newResult *ListBucketResult) *DiffResult {
+	oldFiles := make(map[string]File, len(oldResult.Files))
+	for _, f := range oldResult.Files {
+		oldFiles[f.Key] = f
+	}
+	newFiles := make(map[string]File, len(newResult.Files))
+	for _, f := range newResult.Files {
+		newFiles[f.Key] = f
+	}
+
+	d := new(DiffResult)
+	for key, n := range newFiles {
+		o, ok := oldFiles[key]
+		if !ok {
+			d.Added = append(d.Added, n)
+			continue
+		}
+		if changes := fileChanges(o, n); len(changes) > 0 {
+			d.Modified = append(d.Modified, Modification{Key: key, Old: o, New: n, Changes: changes})
+		} else {
+			d.Totals.Unchanged++
+		}
+	}
+	for key, o := range oldFiles {
+		if _, ok := newFiles[key]; !ok {
+			d.Removed = append(d.Removed, o)
+		}
+	}
+	sortFilesByKey(d.Added)
+	sortFilesByKey(d.Removed)
+	sort.Slice(d.Modified, func(i, j int) bool { return d.Modified[i].Key < d.Modified[j].Key })
+	d.detectRenames()
+
+	d.Totals.Old = len(oldFiles)
+	d.Totals.New = len(newFiles)
+	d.Totals.Added = len(d.Added)
+	d.Totals.Removed = len(d.Removed)
+	d.Totals.Modified = len(d.Modified)
+	d.Totals.Renamed = len(d.Renamed)
+	for _, f := range d.Added {
+		d.Totals.AddedSize += int64(f.Size)
+	}
+	for _, f := range d.Removed {
+		d.Totals.RemovedSize += int64(f.Size)
+	}
+	for _, m := range d.Modified {
+		d.Totals.ModifiedSize += int64(m.New.Size) - int64(m.Old.Size)
+	}
+	return d
+}
+
+// fileChanges 比较同一个 Key 的两个版本；修改时间只在两边都有值时比较
+func fileChanges(o File, n File) []string {
+	var changes []string
+	if o.Size != n.Size {
+		changes = append(changes, "Size")
+	}
+	if o.ETag != "" && n.ETag != "" && !strings.EqualFold(o.ETag, n.ETag) {
+		changes = append(changes, "ETag")
+	}
+	if !o.LastModified.IsZero() && !n.LastModified.IsZero() && !o.LastModified.Equal(n.LastModified) {
+		changes = append(changes, "LastModified")
+	}
+	return changes
+}
+
+// detectRenames 删除的和新增的里面，ETag、大小都相同的配成一对，视为改名
+func (d *DiffResult) detectRenames() {
+	type identity struct {
+		etag string
+		size int
+	}
+	removed := make(map[identity][]int)
+	for i, f := range d.Removed {
+		if f.ETag == "" || strings.HasSuffix(f.Key, "/") {
+			continue
+		}
+		id := identity{strings.ToLower(f.ETag), f.Size}
+		removed[id] = append(removed[id], i)
+	}
+
+	usedRemoved := make(map[int]bool)
+	var added []File
+	for _, f := range d.Added {
+		id := identity{strings.ToLower(f.ETag), f.Size}
+		if candidates := removed[id]; f.ETag != "" && len(candidates) > 0 {
+			i := candidates[0]
+			removed[id] = candidates[1:]
+			usedRemoved[i] = true
+			d.Renamed = append(d.Renamed, Rename{From: d.Removed[i], To: f})
+			continue
+		}
+		added = append(added, f)
+	}
+	var remaining []File
+	for i, f := range d.Removed {
+		if !usedRemoved[i] {
+			remaining = append(remaining, f)
+		}
+	}
+	d.Added, d.Removed = added, remaining
+}
+
+func sortFilesByKey(files []File) {
+	sort.Slice(files, func(i, j int) bool { return files[i].Key < files[j].Key })
+}
+
+// PrintDiff 以表格形式打印差异，最后一行是汇总
+func PrintDiff(w io.Writer, d *DiffResult) error {
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "Change\tKey\tSize\tLastModifiedDate\tDetail")
+	for _, f := range d.Added {
+		fmt.Fprintf(writer, "added\t%s\t%d\t%s\t\n", f.Key, f.Size, FormatTime(f.LastModified))
+	}
+	for _, f := range d.Removed {
+		fmt.Fprintf(writer, "removed\t%s\t%d\t%s\t\n", f.Key, f.Size, FormatTime(f.LastModified))
+	}
+	for _, m := range d.Modified {
+		fmt.Fprintf(writer, "modified\t%s\t%d\t%s\t%s\n", m.Key, m.New.Size, FormatTime(m.New.LastModified), modificationDetail(m))
+	}
+	for _, r := range d.Renamed {
+		fmt.Fprintf(writer, "renamed\t%s\t%d\t%s\tfrom %s\n", r.To.Key, r.To.Size, FormatTime(r.To.LastModified), r.From.Key)
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+
+	t := d.Totals
+	_, err := fmt.Fprintf(w, "\nold=%d new=%d added=%d (%s) removed=%d (%s) modified=%d renamed=%d unchanged=%d\n",
+		t.Old, t.New, t.Added, HumanSize(t.AddedSize), t.Removed, HumanSize(t.RemovedSize), t.Modified, t.Renamed, t.Unchanged)
+	return err
+}
+
+func modificationDetail(m Modification) string {
+	var parts []string
+	for _, c := range m.Changes {
+		switch c {
+		case "Size":
+			parts = append(parts, fmt.Sprintf("Size %d -> %d", m.Old.Size, m.New.Size))
+		case "ETag":
+			parts = append(parts, fmt.Sprintf("ETag %s -> %s", m.Old.ETag, m.New.ETag))
+		case "LastModified":
+			parts = append(parts, fmt.Sprintf("LastModified %s -> %s", FormatTime(m.Old.LastModified), FormatTime(m.New.LastModified)))
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// WriteDiffJSON 以 JSON 输出差异
+func WriteDiffJSON(w io.Writer, d *DiffResult) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(d)
+}
+
+var diffTemplate = template.Must(template.New("diff").Funcs(template.FuncMap{
+	"time":   FormatTime,
+	"human":  HumanSize,
+	"detail": modificationDetail,
+	"size":   func(n int) string { return HumanSize(int64(n)) },
+}).Parse(`<!DOCTYPE html>
+<html lang="{{.Lang}}">
+<head>
+<meta charset="UTF-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border: 1px solid #ddd; padding: 4px 8px; text-align: left; font-size: 14px; }
+th { background: #f5f5f5; }
+.added { color: #1a7f37; } .removed { color: #cf222e; } .modified { color: #9a6700; } .renamed { color: #0969da; }
+</style>
+</head>
+<body>
+<h2>{{.Title}}</h2>
+{{with .Diff.Totals}}<p>old={{.Old}} new={{.New}} <span class="added">added={{.Added}} ({{human .AddedSize}})</span> <span class="removed">removed={{.Removed}} ({{human .RemovedSize}})</span> <span class="modified">modified={{.Modified}}</span> <span class="renamed">renamed={{.Renamed}}</span> unchanged={{.Unchanged}}</p>{{end}}
+<table>
+<tr><th>Change</th><th>Key</th><th>Size</th><th>LastModified</th><th>Detail</th></tr>
+{{range .Diff.Added}}<tr class="added"><td>added</td><td>{{if .Link}}<a href="{{.Link}}">{{.Key}}</a>{{else}}{{.Key}}{{end}}</td><td>{{size .Size}}</td><td>{{time .LastModified}}</td><td></td></tr>
+{{end}}{{range .Diff.Removed}}<tr class="removed"><td>removed</td><td>{{.Key}}</td><td>{{size .Size}}</td><td>{{time .LastModified}}</td><td></td></tr>
+{{end}}{{range .Diff.Modified}}<tr class="modified"><td>modified</td><td>{{if .New.Link}}<a href="{{.New.Link}}">{{.Key}}</a>{{else}}{{.Key}}{{end}}</td><td>{{size .New.Size}}</td><td>{{time .New.LastModified}}</td><td>{{detail .}}</td></tr>
+{{end}}{{range .Diff.Renamed}}<tr class="renamed"><td>renamed</td><td>{{if .To.Link}}<a href="{{.To.Link}}">{{.To.Key}}</a>{{else}}{{.To.Key}}{{end}}</td><td>{{size .To.Size}}</td><td>{{time .To.LastModified}}</td><td>from {{.From.Key}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// WriteDiffHTML 以 HTML 页面输出差异，<html lang> 跟随当前语言
+func WriteDiffHTML(w io.Writer, d *DiffResult, title string) error {
+	htmlLang := "en"
+	if Lang() == LangZH {
+		htmlLang = "zh-CN"
+	}
+	return diffTemplate.Execute(w, struct {
+		Lang  string
+		Title string
+		Diff  *DiffResult
+	}{htmlLang, title, d})
+}
+
+// WriteDiff 按格式输出差异：table、json、html
+func WriteDiff(w io.Writer, d *DiffResult, format string, title string) error {
+	switch strings.ToLower(format) {
+	case "", "table":
+		return PrintDiff(w, d)
+	case "json":
+		return WriteDiffJSON(w, d)
+	case "html":
+		return WriteDiffHTML(w, d, title)
+	}
+	return fmt.Errorf("unknown diff format: %v", format)
+}
+
+// SaveDiff 按扩展名保存差异：.json、.html，其余保存为表格文本
+func SaveDiff(d *DiffResult, filePath string, title string) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("Failed to create output file: %w", err)
+	}
+	defer file.Close()
+
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(filePath)), ".")
+	if format == "htm" {
+		format = "html"
+	}
+	if format != "json" && format != "html" {
+		format = "table"
+	}
+	return WriteDiff(file, d, format, title)
+}
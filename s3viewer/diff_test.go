@@ -0,0 +1,111 @@
+package s3viewer
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	day := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	oldResult := &ListBucketResult{Files: []File{
+		{Key: "same.txt", Size: 10, ETag: "aaa", LastModified: day},
+		{Key: "grown.log", Size: 10, ETag: "bbb", LastModified: day},
+		{Key: "touched.txt", Size: 10, ETag: "ccc", LastModified: day},
+		{Key: "old/backup.zip", Size: 1000, ETag: "ddd", LastModified: day},
+		{Key: "gone.sql", Size: 50, ETag: "eee", LastModified: day},
+	}}
+	newResult := &ListBucketResult{Files: []File{
+		{Key: "same.txt", Size: 10, ETag: "AAA", LastModified: day},
+		{Key: "grown.log", Size: 30, ETag: "fff", LastModified: day.Add(time.Hour)},
+		{Key: "touched.txt", Size: 10, ETag: "ccc", LastModified: day.Add(time.Hour)},
+		{Key: "new/backup.zip", Size: 1000, ETag: "ddd", LastModified: day},
+		{Key: "fresh.env", Size: 5, ETag: "ggg", LastModified: day},
+	}}
+
+	d := Diff(oldResult, newResult)
+	assert.Equal(t, []string{"fresh.env"}, diffKeys(d.Added))
+	assert.Equal(t, []string{"gone.sql"}, diffKeys(d.Removed))
+	assert.Len(t, d.Modified, 2)
+	assert.Equal(t, "grown.log", d.Modified[0].Key)
+	assert.Equal(t, []string{"Size", "ETag", "LastModified"}, d.Modified[0].Changes)
+	assert.Equal(t, []string{"LastModified"}, d.Modified[1].Changes)
+	assert.Len(t, d.Renamed, 1)
+	assert.Equal(t, "old/backup.zip", d.Renamed[0].From.Key)
+	assert.Equal(t, "new/backup.zip", d.Renamed[0].To.Key)
+
+	assert.Equal(t, DiffTotals{Old: 5, New: 5, Added: 1, Removed: 1, Modified: 2, Renamed: 1, Unchanged: 1,
+		AddedSize: 5, RemovedSize: 50, ModifiedSize: 20}, d.Totals)
+	assert.False(t, d.Empty())
+	assert.True(t, Diff(oldResult, oldResult).Empty())
+}
+
+func TestDiffOutput(t *testing.T) {
+	oldResult := &ListBucketResult{Files: []File{{Key: "a.txt", Size: 1, ETag: "x"}}}
+	newResult := &ListBucketResult{Files: []File{{Key: "<b>.txt", Size: 2, ETag: "y"}}}
+	d := Diff(oldResult, newResult)
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteDiff(&buf, d, "table", ""))
+	assert.Contains(t, buf.String(), "added")
+	assert.Contains(t, buf.String(), "removed")
+	assert.Contains(t, buf.String(), "added=1")
+
+	buf.Reset()
+	assert.NoError(t, WriteDiff(&buf, d, "json", ""))
+	var decoded DiffResult
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, d.Totals, decoded.Totals)
+
+	buf.Reset()
+	assert.NoError(t, WriteDiff(&buf, d, "html", "weekly"))
+	assert.Contains(t, buf.String(), "&lt;b&gt;.txt")
+	assert.NotContains(t, buf.String(), "<b>.txt")
+	assert.Contains(t, buf.String(), `<html lang="en">`)
+
+	// <html lang> 跟随 -lang
+	defer SetLang(LangEN)
+	assert.NoError(t, SetLang(LangZH))
+	buf.Reset()
+	assert.NoError(t, WriteDiff(&buf, d, "html", "weekly"))
+	assert.Contains(t, buf.String(), `<html lang="zh-CN">`)
+
+	assert.Error(t, WriteDiff(&buf, d, "xml", ""))
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	// 和 SaveResultToCSVFile 导出的文件配合使用
+	result, err := parseXMLToListBucketResult(sanitizeXMLContent([]byte(specialXML)))
+	if err != nil {
+		t.Fatalf("Failed to parse XML to ListBucketResult: %v", err)
+	}
+	result, _ = result.MergeUrlAndFillLinks("http://s3.example.com/")
+
+	dir := t.TempDir()
+	oldPath := filepath.Join(dir, "old.csv")
+	if err := SaveResultToCSVFile(result, oldPath); err != nil {
+		t.Fatalf("Failed to save CSV: %v", err)
+	}
+	previous, err := LoadSnapshot(oldPath)
+	if err != nil {
+		t.Fatalf("Failed to load CSV: %v", err)
+	}
+
+	d := Diff(previous, result)
+	assert.True(t, d.Empty())
+	assert.Equal(t, len(result.Files), d.Totals.Unchanged)
+
+	htmlPath := filepath.Join(dir, "diff.html")
+	assert.NoError(t, SaveDiff(d, htmlPath, "weekly"))
+}
+
+func diffKeys(files []File) []string {
+	var keys []string
+	for _, f := range files {
+		keys = append(keys, f.Key)
+	}
+	return keys
+}
@@ -0,0 +1,278 @@
+package s3viewer
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// 探测结果
+const (
+	BucketNotFound     = "NoSuchBucket"
+	BucketAccessDenied = "AccessDenied" // bucket 存在，但不能列目录
+	BucketListable     = "Listable"
+	BucketUnknown      = "Unknown"
+)
+
+// DefaultPermutationWords 常见的 bucket 名前缀、后缀
+var DefaultPermutationWords = []string{
+	"dev", "test", "prod", "staging", "uat", "pre", "backup", "backups", "bak", "static", "assets",
+	"files", "upload", "uploads", "data", "logs", "log", "img", "images", "media", "cdn", "public",
+	"private", "db", "www", "web", "app", "api", "archive", "tmp", "oss", "s3", "resource", "download",
+}
+
+// NameGenerator 根据公司关键字生成候选的 bucket 名
+type NameGenerator struct {
+	Keywords   []string // 公司关键字，例如 acme
+	Words      []string // 前缀/后缀词表，为空时使用 DefaultPermutationWords
+	Years      []string // 追加在后面的年份，例如 2023、2024
+	Regions    []string // 追加在后面的地域，例如 cn-hangzhou、ap-east-1
+	Separators []string // 连接符，为空时使用 "-" 和 ""
+}
+
+var bucketNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+
+// ValidBucketName 是否符合 S3/OSS/COS 共同的 bucket 命名规则：3-63 个字符，小写字母、数字、'-'、'.'，首尾是字母或数字
+func ValidBucketName(name string) bool {
+	return bucketNamePattern.MatchString(name) && !strings.Contains(name, "..") && net.ParseIP(name) == nil
+}
+
+// Generate 生成去重后的候选名，关键字本身排在最前面
+func (g *NameGenerator) Generate() []string {
+	words := g.Words
+	if len(words) == 0 {
+		words = DefaultPermutationWords
+	}
+	separators := g.Separators
+	if len(separators) == 0 {
+		separators = []string{"-", ""}
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		name = strings.ToLower(name)
+		if !seen[name] && ValidBucketName(name) {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	for _, keyword := range g.Keywords {
+		keyword = strings.TrimSpace(keyword)
+		if keyword == "" {
+			continue
+		}
+		add(keyword)
+		for _, sep := range separators {
+			for _, w := range words {
+				add(keyword + sep + w)
+				add(w + sep + keyword)
+			}
+			for _, suffix := range append(append([]string{}, g.Years...), g.Regions...) {
+				add(keyword + sep + suffix)
+				for _, w := range words {
+					add(keyword + sep + w + sep + suffix)
+				}
+			}
+		}
+	}
+	return names
+}
+
+// ExpandTemplate 把模板里的 {name} 换成 bucket 名，例如 https://{name}.s3.amazonaws.com
+func ExpandTemplate(template string, name string) string {
+	return strings.ReplaceAll(template, "{name}", name)
+}
+
+// ProbeResult 单个候选的探测结果
+type ProbeResult struct {
+	Name    string
+	Url     string
+	Status  string // NoSuchBucket、AccessDenied、Listable、Unknown
+	Code    string `json:",omitempty"` // 错误响应里的 <Code>
+	HTTP    int    `json:",omitempty"`
+	Objects int    `json:",omitempty"`
+	Err     string `json:",omitempty"`
+}
+
+// Exists bucket 是否存在（能列目录或者拒绝访问）
+func (r ProbeResult) Exists() bool {
+	return r.Status == BucketListable || r.Status == BucketAccessDenied
+}
+
+// Prober 并发探测候选 bucket
+type Prober struct {
+	Client  *http.Client // 为 nil 时使用 NewHTTPClient(15s)
+	Workers int          // 并发数，<=0 时为 20
+}
+
+// Candidate 候选 bucket 名和对应的 URL
+type Candidate struct {
+	Name string
+	Url  string
+}
+
+// Candidates 每个名字套用每个模板
+func Candidates(names []string, templates []string) []Candidate {
+	var candidates []Candidate
+	for _, template := range templates {
+		for _, name := range names {
+			candidates = append(candidates, Candidate{Name: name, Url: ExpandTemplate(template, name)})
+		}
+	}
+	return candidates
+}
+
+// Probe 探测所有候选，结果和 candidates 一一对应
+func (p *Prober) Probe(candidates []Candidate) []ProbeResult {
+	return p.ProbeContext(context.Background(), candidates)
+}
+
+// ProbeContext 同 Probe；ctx 取消后不再发起新的探测，没来得及探测的候选状态为 Unknown
+func (p *Prober) ProbeContext(ctx context.Context, candidates []Candidate) []ProbeResult {
+	workers := p.Workers
+	if workers <= 0 {
+		workers = 20
+	}
+	client := p.Client
+	if client == nil {
+		client = NewHTTPClient(15 * time.Second)
+	}
+
+	results := make([]ProbeResult, len(candidates))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = probeOne(ctx, client, candidates[i])
+				if results[i].Exists() {
+					Logger().Info(T("bucket found"), "status", results[i].Status, "url", results[i].Url)
+				}
+			}
+		}()
+	}
+	fed := feedJobs(ctx, jobs, len(candidates))
+	wg.Wait()
+	for i := fed; i < len(candidates); i++ {
+		results[i] = ProbeResult{Name: candidates[i].Name, Url: candidates[i].Url, Status: BucketUnknown, Err: ctx.Err().Error()}
+	}
+	return results
+}
+
+// s3Error S3 兼容接口的错误响应
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+var s3ErrorPattern = regexp.MustCompile(`(?s)<Error>.*?</Error>`)
+
+func probeOne(ctx context.Context, client *http.Client, c Candidate) ProbeResult {
+	result := ProbeResult{Name: c.Name, Url: c.Url, Status: BucketUnknown}
+	req, err := NewRequestContext(ctx, "GET", c.Url)
+	if err != nil {
+		result.Err = err.Error()
+		return result
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		// 虚拟主机风格下，不存在的 bucket 域名解析不出来
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			result.Status = BucketNotFound
+			return result
+		}
+		result.Err = err.Error()
+		return result
+	}
+	defer resp.Body.Close()
+	result.HTTP = resp.StatusCode
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		result.Err = err.Error()
+		return result
+	}
+	classifyProbe(&result, body)
+	return result
+}
+
+// classifyProbe 先看能不能解析出文件列表，再看错误码，最后看状态码
+func classifyProbe(result *ProbeResult, body []byte) {
+	if xmlContent, err := findS3XMLString(string(body)); err == nil {
+		if listing, err := parseXMLToListBucketResult(sanitizeXMLContent(xmlContent)); err == nil {
+			result.Status = BucketListable
+			result.Objects = len(listing.Files)
+			return
+		}
+	}
+
+	if match := s3ErrorPattern.Find(body); match != nil {
+		var e s3Error
+		if xml.Unmarshal(match, &e) == nil {
+			result.Code = e.Code
+		}
+	}
+	switch result.Code {
+	case "NoSuchBucket", "InvalidBucketName", "NoSuchDomain":
+		result.Status = BucketNotFound
+		return
+	case "AccessDenied", "AllAccessDisabled", "AccountProblem", "UserDisable", "PermanentRedirect", "AuthorizationHeaderMalformed":
+		// 重定向到其他地域、地域不对，说明 bucket 存在
+		result.Status = BucketAccessDenied
+		return
+	}
+	switch {
+	case result.HTTP == http.StatusNotFound:
+		result.Status = BucketNotFound
+	case result.HTTP == http.StatusForbidden || result.HTTP == http.StatusMovedPermanently:
+		result.Status = BucketAccessDenied
+	}
+}
+
+// PrintProbeResults 以表格形式打印存在的 bucket
+func PrintProbeResults(w io.Writer, results []ProbeResult) error {
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "Status\tName\tUrl\tObjects\tCode")
+	for _, r := range results {
+		if r.Exists() {
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%d\t%s\n", r.Status, r.Name, r.Url, r.Objects, r.Code)
+		}
+	}
+	return writer.Flush()
+}
+
+// WriteProbeTargets 每行输出一个 URL，可以直接作为 `-l` 的输入；withDenied 为 true 时也输出拒绝访问的 bucket
+func WriteProbeTargets(w io.Writer, results []ProbeResult, withDenied bool) error {
+	for _, r := range results {
+		if r.Status == BucketListable || withDenied && r.Status == BucketAccessDenied {
+			if _, err := fmt.Fprintln(w, listingURL(r.Url)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// listingURL 保证 URL 以 / 结尾，和 -u 的写法一致
+func listingURL(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil || parsed.RawQuery != "" || strings.HasSuffix(parsed.Path, "/") {
+		return u
+	}
+	parsed.Path += "/"
+	return parsed.String()
+}
@@ -0,0 +1,82 @@
+package s3viewer
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNameGenerator(t *testing.T) {
+	g := &NameGenerator{Keywords: []string{"Acme", "x"}, Words: []string{"dev", "backup"}, Years: []string{"2024"}, Regions: []string{"cn-hangzhou"}}
+	names := g.Generate()
+	assert.Equal(t, "acme", names[0])
+	for _, name := range []string{"acme-dev", "backup-acme", "acmedev", "acme-2024", "acme-backup-2024", "acme-cn-hangzhou", "x-dev"} {
+		assert.Contains(t, names, name)
+	}
+	// 太短的不要
+	assert.NotContains(t, names, "x")
+
+	seen := make(map[string]bool)
+	for _, name := range names {
+		assert.False(t, seen[name], name)
+		seen[name] = true
+		assert.True(t, ValidBucketName(name), name)
+	}
+}
+
+func TestValidBucketName(t *testing.T) {
+	assert.True(t, ValidBucketName("acme-prod.logs"))
+	assert.False(t, ValidBucketName("Acme"))
+	assert.False(t, ValidBucketName("-acme"))
+	assert.False(t, ValidBucketName("acme..logs"))
+	assert.False(t, ValidBucketName("192.168.1.1"))
+	assert.False(t, ValidBucketName(strings.Repeat("a", 64)))
+}
+
+func TestProber(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch strings.Trim(r.URL.Path, "/") {
+		case "acme-backup":
+			fmt.Fprint(w, bucketXML(map[string]string{"db.sql": "aaaa"}))
+		case "acme-dev":
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`)
+		case "acme-prod":
+			w.WriteHeader(http.StatusMovedPermanently)
+			fmt.Fprint(w, `<Error><Code>PermanentRedirect</Code><Endpoint>acme-prod.s3.eu-west-1.amazonaws.com</Endpoint></Error>`)
+		case "acme-tmp":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `<Error><Code>NoSuchBucket</Code></Error>`)
+		}
+	}))
+	defer server.Close()
+
+	names := []string{"acme", "acme-backup", "acme-dev", "acme-prod", "acme-tmp"}
+	candidates := Candidates(names, []string{server.URL + "/{name}"})
+	results := (&Prober{Workers: 3}).Probe(candidates)
+
+	var statuses []string
+	for _, r := range results {
+		statuses = append(statuses, r.Status)
+	}
+	assert.Equal(t, []string{BucketNotFound, BucketListable, BucketAccessDenied, BucketAccessDenied, BucketUnknown}, statuses)
+	assert.Equal(t, 1, results[1].Objects)
+	assert.Equal(t, "AccessDenied", results[2].Code)
+
+	// 输出可以直接作为 -l 的输入
+	var buf bytes.Buffer
+	assert.NoError(t, WriteProbeTargets(&buf, results, false))
+	targets, err := ReadTargets(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{server.URL + "/acme-backup/"}, targets)
+
+	buf.Reset()
+	assert.NoError(t, WriteProbeTargets(&buf, results, true))
+	assert.Equal(t, 3, strings.Count(buf.String(), "\n"))
+}
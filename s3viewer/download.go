@@ -0,0 +1,496 @@
+package s3viewer
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 下载结果的状态
+const (
+	DownloadOK      = "downloaded"
+	DownloadResumed = "resumed"
+	DownloadSkipped = "skipped"
+	DownloadFailed  = "failed"
+)
+
+// Downloader 把对象镜像到本地目录，保留 Key 的目录结构
+type Downloader struct {
+	Dir       string       // 本地目录
+	Workers   int          // 并发数，<=0 时为 4
+	RateLimit int64        // 所有 worker 合计的带宽上限（字节/秒），0 表示不限速
+	Progress  io.Writer    // 进度输出，nil 表示不显示
+	Client    *http.Client // 为 nil 时使用 NewHTTPClient(0)
+
+	Verify        bool   // 下载后用 ETag 校验内容
+	PartSize      int64  // 分片上传的分片大小，0 表示自动推断
+	QuarantineDir string // 校验失败的文件挪到这里，为空时使用 Dir/.quarantine
+}
+
+// DownloadResult 单个对象的下载结果
+type DownloadResult struct {
+	Key    string
+	Path   string
+	Status string
+	Bytes  int64 // 本次实际传输的字节数
+	Err    error
+
+	ETag   string // 列表里的 ETag
+	Verify string // 校验结果：ok、mismatch、unverified，没开启校验时为空
+	Actual string // 实际算出的 MD5 / 分片 ETag
+}
+
+// Download 并发下载 files，返回每个对象的结果（顺序和 files 一致）
+func (d *Downloader) Download(files []File) []DownloadResult {
+	return d.DownloadContext(context.Background(), files)
+}
+
+// DownloadContext 同 Download；ctx 取消后不再开始新的下载，正在下载的中断并保留 .part 以便续传，
+// 没来得及下载的对象状态为 failed，Err 为 ctx.Err()
+func (d *Downloader) DownloadContext(ctx context.Context, files []File) []DownloadResult {
+	workers := d.Workers
+	if workers <= 0 {
+		workers = 4
+	}
+	client := d.Client
+	if client == nil {
+		client = NewHTTPClient(0)
+	}
+	limiter := newRateLimiter(d.RateLimit)
+	progress := newDownloadProgress(d.Progress, len(files))
+	defer progress.stop()
+
+	results := make([]DownloadResult, len(files))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = d.downloadOne(ctx, client, limiter, progress, files[i])
+				if results[i].Err != nil {
+					Logger().Warn(T("download failed"), "key", files[i].Key, "err", results[i].Err)
+				}
+				progress.fileDone()
+			}
+		}()
+	}
+	fed := feedJobs(ctx, jobs, len(files))
+	wg.Wait()
+	for i := fed; i < len(files); i++ {
+		results[i] = DownloadResult{Key: files[i].Key, ETag: files[i].ETag, Status: DownloadFailed, Err: ctx.Err()}
+	}
+	return results
+}
+
+func (d *Downloader) downloadOne(ctx context.Context, client *http.Client, limiter *rateLimiter, progress *downloadProgress, file File) DownloadResult {
+	result := DownloadResult{Key: file.Key, ETag: file.ETag}
+	fail := func(err error) DownloadResult {
+		result.Status = DownloadFailed
+		result.Err = err
+		return result
+	}
+
+	localPath, err := SafeLocalPath(d.Dir, file.Key)
+	if err != nil {
+		return fail(err)
+	}
+	result.Path = localPath
+
+	// 压缩包里的文件没法单独下载
+	if file.Archive != "" {
+		result.Status = DownloadSkipped
+		return result
+	}
+	// 目录占位对象只建目录
+	if strings.HasSuffix(file.Key, "/") {
+		if err := os.MkdirAll(localPath, 0755); err != nil {
+			return fail(err)
+		}
+		result.Status = DownloadSkipped
+		return result
+	}
+	if file.Link == "" {
+		return fail(fmt.Errorf("no link for %v", file.Key))
+	}
+	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		return fail(err)
+	}
+
+	// 本地已经有相同的文件就跳过
+	if same, err := isSameLocalFile(localPath, file); err != nil {
+		return fail(err)
+	} else if same {
+		result.Status = DownloadSkipped
+		if d.Verify && file.ETag != "" && !IsMultipartETag(file.ETag) {
+			// isSameLocalFile 已经比对过 MD5
+			result.Verify, result.Actual = VerifyOK, strings.ToLower(file.ETag)
+		}
+		return result
+	}
+
+	// 有 .part 说明上次没下完，用 Range 续传。带上 If-Range，对象在这期间被覆盖过的话服务端返回整个新对象；
+	// 没有 ETag 也没有修改时间时确认不了 .part 是不是同一个对象，从头下载
+	partPath := localPath + ".part"
+	ifRange := ifRangeValue(file)
+	var offset int64
+	if info, err := os.Stat(partPath); err == nil && ifRange != "" {
+		offset = info.Size()
+		if file.Size > 0 && offset >= int64(file.Size) {
+			offset = 0
+		}
+	}
+
+	req, err := NewRequestContext(ctx, "GET", file.Link)
+	if err != nil {
+		return fail(err)
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", ifRange)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fail(err)
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch {
+	case offset > 0 && resp.StatusCode == http.StatusPartialContent:
+		// 返回的不是从 offset 开始的部分时不能接在 .part 后面，删掉 .part 下次从头下载
+		if start, ok := contentRangeStart(resp.Header.Get("Content-Range")); !ok || start != offset {
+			os.Remove(partPath)
+			return fail(fmt.Errorf("unexpected Content-Range %q, want start %d", resp.Header.Get("Content-Range"), offset))
+		}
+		flags |= os.O_APPEND
+		result.Status = DownloadResumed
+	case resp.StatusCode == http.StatusOK:
+		// 服务端不支持 Range，从头开始
+		flags |= os.O_TRUNC
+		result.Status = DownloadOK
+	default:
+		return fail(fmt.Errorf("unexpected status: %v", resp.Status))
+	}
+
+	out, err := os.OpenFile(partPath, flags, 0644)
+	if err != nil {
+		return fail(err)
+	}
+
+	// 普通对象的 ETag 就是 MD5，边下载边算；续传时先把已有的部分算进去
+	var dst io.Writer = out
+	var hasher hash.Hash
+	if d.Verify && file.ETag != "" && !IsMultipartETag(file.ETag) {
+		hasher = md5.New()
+		if result.Status == DownloadResumed {
+			if err := hashFilePrefix(hasher, partPath, offset); err != nil {
+				out.Close()
+				return fail(err)
+			}
+		}
+		dst = io.MultiWriter(out, hasher)
+	}
+
+	n, err := io.Copy(dst, &meteredReader{ctx: ctx, r: resp.Body, limiter: limiter, progress: progress})
+	result.Bytes = n
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fail(err)
+	}
+
+	if err := os.Rename(partPath, localPath); err != nil {
+		return fail(err)
+	}
+
+	if d.Verify {
+		if hasher != nil {
+			result.Actual = hex.EncodeToString(hasher.Sum(nil))
+			result.Verify = VerifyMismatch
+			if strings.EqualFold(result.Actual, file.ETag) {
+				result.Verify = VerifyOK
+			}
+		} else if result.Verify, result.Actual, err = VerifyFile(localPath, file.ETag, d.PartSize); err != nil {
+			return fail(err)
+		}
+		if result.Verify == VerifyMismatch {
+			Logger().Warn(T("verify failed"), "key", file.Key, "etag", file.ETag, "actual", result.Actual)
+			quarantined, err := quarantineFile(localPath, d.quarantineDir(), file.Key)
+			if err != nil {
+				return fail(err)
+			}
+			result.Path = quarantined
+		}
+	}
+	return result
+}
+
+// ifRangeValue 续传时用的 If-Range：优先用 ETag，没有时用修改时间
+func ifRangeValue(file File) string {
+	if file.ETag != "" {
+		return `"` + file.ETag + `"`
+	}
+	if !file.LastModified.IsZero() {
+		return file.LastModified.UTC().Format(http.TimeFormat)
+	}
+	return ""
+}
+
+// contentRangeStart 取出 Content-Range: bytes 100-199/1000 里的起始位置
+func contentRangeStart(contentRange string) (int64, bool) {
+	spec, ok := strings.CutPrefix(contentRange, "bytes ")
+	if !ok {
+		return 0, false
+	}
+	start, _, ok := strings.Cut(spec, "-")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(start), 10, 64)
+	return n, err == nil
+}
+
+func (d *Downloader) quarantineDir() string {
+	if d.QuarantineDir != "" {
+		return d.QuarantineDir
+	}
+	return filepath.Join(d.Dir, ".quarantine")
+}
+
+// hashFilePrefix 把文件前 n 个字节写进 h
+func hashFilePrefix(h hash.Hash, path string, n int64) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.CopyN(h, f, n)
+	return err
+}
+
+// isSameLocalFile 本地文件大小一致，并且（对于非分片上传的对象）MD5 和 ETag 一致
+func isSameLocalFile(localPath string, file File) (bool, error) {
+	info, err := os.Stat(localPath)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if info.IsDir() {
+		return false, fmt.Errorf("%v is a directory", localPath)
+	}
+	if info.Size() != int64(file.Size) {
+		return false, nil
+	}
+	if file.ETag == "" || IsMultipartETag(file.ETag) {
+		return true, nil
+	}
+
+	sum, err := fileMD5(localPath)
+	if err != nil {
+		return false, err
+	}
+	return strings.EqualFold(sum, file.ETag), nil
+}
+
+func fileMD5(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// SafeLocalPath 把对象 Key 转换为 dir 下的本地路径，拒绝 `../` 之类的路径穿越
+func SafeLocalPath(dir string, key string) (string, error) {
+	if strings.ContainsRune(key, 0) {
+		return "", fmt.Errorf("unsafe key %q: contains NUL", key)
+	}
+
+	var parts []string
+	for _, part := range strings.Split(strings.ReplaceAll(key, "\\", "/"), "/") {
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			return "", fmt.Errorf("unsafe key %q: path traversal", key)
+		}
+		parts = append(parts, sanitizePathSegment(part))
+	}
+	if len(parts) == 0 {
+		return "", fmt.Errorf("unsafe key %q: empty path", key)
+	}
+
+	base, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	localPath := filepath.Join(base, filepath.Join(parts...))
+	if rel, err := filepath.Rel(base, localPath); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("unsafe key %q: escapes %v", key, dir)
+	}
+	return localPath, nil
+}
+
+// sanitizePathSegment 替换 Windows 文件名里不允许的字符和控制字符
+func sanitizePathSegment(segment string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`<>:"|?*`, r) {
+			return '_'
+		}
+		return r
+	}, segment)
+}
+
+// feedJobs 把 0..n-1 依次发给 worker，ctx 取消时提前停止；关闭 jobs，返回发出去的个数
+func feedJobs(ctx context.Context, jobs chan<- int, n int) int {
+	defer close(jobs)
+	for i := 0; i < n; i++ {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			return i
+		}
+	}
+	return n
+}
+
+// rateLimiter 简单的令牌桶，所有 worker 共用
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(bytesPerSecond int64) *rateLimiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	return &rateLimiter{rate: float64(bytesPerSecond), tokens: float64(bytesPerSecond), last: time.Now()}
+}
+
+// wait 消耗 n 个令牌，不够时等到够为止；ctx 取消时不再等，返回 ctx.Err()
+func (l *rateLimiter) wait(ctx context.Context, n int) error {
+	if l == nil || n <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.rate {
+		l.tokens = l.rate
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	var sleep time.Duration
+	if l.tokens < 0 {
+		sleep = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+	if sleep <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(sleep)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// meteredReader 读取时限速并统计进度
+type meteredReader struct {
+	ctx      context.Context
+	r        io.Reader
+	limiter  *rateLimiter
+	progress *downloadProgress
+}
+
+func (m *meteredReader) Read(p []byte) (int, error) {
+	if m.limiter != nil && len(p) > 32*1024 {
+		p = p[:32*1024]
+	}
+	n, err := m.r.Read(p)
+	if waitErr := m.limiter.wait(m.ctx, n); waitErr != nil {
+		err = waitErr
+	}
+	m.progress.add(n)
+	return n, err
+}
+
+// downloadProgress 定时把下载进度打印到 w
+type downloadProgress struct {
+	w     io.Writer
+	total int
+	files atomic.Int64
+	bytes atomic.Int64
+	start time.Time
+	done  chan struct{}
+	wg    sync.WaitGroup
+}
+
+func newDownloadProgress(w io.Writer, total int) *downloadProgress {
+	p := &downloadProgress{w: w, total: total, start: time.Now(), done: make(chan struct{})}
+	if w == nil {
+		return p
+	}
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(500 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.print("\r")
+			case <-p.done:
+				p.print("\r")
+				fmt.Fprintln(p.w)
+				return
+			}
+		}
+	}()
+	return p
+}
+
+func (p *downloadProgress) add(n int) {
+	p.bytes.Add(int64(n))
+}
+
+func (p *downloadProgress) fileDone() {
+	p.files.Add(1)
+}
+
+func (p *downloadProgress) print(prefix string) {
+	bytes := p.bytes.Load()
+	speed := float64(bytes) / time.Since(p.start).Seconds()
+	fmt.Fprintf(p.w, "%s[%d/%d] %s  %s/s   ", prefix, p.files.Load(), p.total, HumanSize(bytes), HumanSize(int64(speed)))
+}
+
+func (p *downloadProgress) stop() {
+	close(p.done)
+	p.wg.Wait()
+}
@@ -0,0 +1,220 @@
+package s3viewer
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// objectServer 支持 Range 的本地对象服务，记录请求数、每次请求的 Range 头和实际返回的字节数
+type objectServer struct {
+	*httptest.Server
+	requests atomic.Int64
+	mu       sync.Mutex
+	ranges   []string
+	sent     int
+}
+
+type countingWriter struct {
+	http.ResponseWriter
+	n *int
+}
+
+func (w countingWriter) Write(p []byte) (int, error) {
+	*w.n += len(p)
+	return w.ResponseWriter.Write(p)
+}
+
+// newObjectServer 启动一个 objectServer，ETag 为内容的 MD5
+func newObjectServer(t *testing.T, objects map[string][]byte) *objectServer {
+	t.Helper()
+	s := new(objectServer)
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requests.Add(1)
+		content, ok := objects[strings.TrimPrefix(r.URL.Path, "/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.ranges = append(s.ranges, r.Header.Get("Range"))
+		w.Header().Set("ETag", `"`+md5Hex(content)+`"`)
+		http.ServeContent(countingWriter{w, &s.sent}, r, r.URL.Path, time.Time{}, bytes.NewReader(content))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func md5Hex(b []byte) string {
+	sum := md5.Sum(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestDownload(t *testing.T) {
+	objects := map[string][]byte{
+		"a.txt":         []byte("hello"),
+		"dir/sub/b.bin": bytes.Repeat([]byte("0123456789"), 10000),
+	}
+	server := newObjectServer(t, objects)
+
+	var files []File
+	for key, content := range objects {
+		files = append(files, File{Key: key, Size: len(content), ETag: md5Hex(content), Link: server.URL + "/" + key})
+	}
+	files = append(files, File{Key: "dir/", Link: server.URL + "/dir/"})
+
+	dir := t.TempDir()
+	var progress bytes.Buffer
+	results := (&Downloader{Dir: dir, Workers: 2, Progress: &progress}).Download(files)
+	for _, r := range results {
+		assert.NoError(t, r.Err, r.Key)
+	}
+	for key, content := range objects {
+		got, err := os.ReadFile(filepath.Join(dir, key))
+		assert.NoError(t, err)
+		assert.Equal(t, content, got, key)
+	}
+	assert.Contains(t, progress.String(), "[3/3]")
+	assert.DirExists(t, filepath.Join(dir, "dir"))
+}
+
+func TestDownloadResumeAndSkip(t *testing.T) {
+	content := bytes.Repeat([]byte("abcdefgh"), 4096)
+	server := newObjectServer(t, map[string][]byte{"big.bin": content})
+	file := File{Key: "big.bin", Size: len(content), ETag: md5Hex(content), Link: server.URL + "/big.bin"}
+
+	// 模拟上次下到一半
+	dir := t.TempDir()
+	localPath := filepath.Join(dir, "big.bin")
+	assert.NoError(t, os.WriteFile(localPath+".part", content[:1000], 0644))
+
+	results := (&Downloader{Dir: dir}).Download([]File{file})
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, DownloadResumed, results[0].Status)
+	assert.Equal(t, int64(len(content)-1000), results[0].Bytes)
+	got, err := os.ReadFile(localPath)
+	assert.NoError(t, err)
+	assert.Equal(t, content, got)
+
+	// 大小和 ETag 都一致，不再请求
+	before := server.requests.Load()
+	results = (&Downloader{Dir: dir}).Download([]File{file})
+	assert.Equal(t, DownloadSkipped, results[0].Status)
+	assert.Equal(t, before, server.requests.Load())
+}
+
+func TestDownloadResumeChanged(t *testing.T) {
+	content := bytes.Repeat([]byte("abcdefgh"), 4096)
+	server := newObjectServer(t, map[string][]byte{"big.bin": content})
+	file := File{Key: "big.bin", Size: len(content), ETag: md5Hex(content), Link: server.URL + "/big.bin"}
+
+	// .part 是对象被覆盖之前下的，ETag 对不上，服务端返回整个对象
+	dir := t.TempDir()
+	localPath := filepath.Join(dir, "big.bin")
+	assert.NoError(t, os.WriteFile(localPath+".part", []byte("old version"), 0644))
+	stale := file
+	stale.ETag = md5Hex([]byte("new version"))
+	results := (&Downloader{Dir: dir}).Download([]File{stale})
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, DownloadOK, results[0].Status)
+	got, _ := os.ReadFile(localPath)
+	assert.Equal(t, content, got)
+}
+
+func TestDownloadResumeContentRange(t *testing.T) {
+	content := []byte("0123456789")
+	// 不管请求的 Range 是什么，都返回从 0 开始的部分
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes 0-4/%d", len(content)))
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write(content[:5])
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	localPath := filepath.Join(dir, "a.bin")
+	assert.NoError(t, os.WriteFile(localPath+".part", content[:3], 0644))
+	file := File{Key: "a.bin", Size: len(content), ETag: md5Hex(content), Link: server.URL + "/a.bin"}
+	results := (&Downloader{Dir: dir}).Download([]File{file})
+	assert.Error(t, results[0].Err)
+	assert.NoFileExists(t, localPath)
+	assert.NoFileExists(t, localPath+".part")
+}
+
+func TestDownloadRateLimit(t *testing.T) {
+	content := bytes.Repeat([]byte("x"), 64*1024)
+	server := newObjectServer(t, map[string][]byte{"slow.bin": content})
+	file := File{Key: "slow.bin", Size: len(content), Link: server.URL + "/slow.bin"}
+
+	start := time.Now()
+	results := (&Downloader{Dir: t.TempDir(), RateLimit: 32 * 1024}).Download([]File{file})
+	assert.NoError(t, results[0].Err)
+	// 令牌桶一开始是满的（32K），剩下的 32K 要等大约 1 秒
+	assert.Greater(t, time.Since(start), 700*time.Millisecond)
+}
+
+func TestDownloadRateLimitCancel(t *testing.T) {
+	content := bytes.Repeat([]byte("x"), 64*1024)
+	server := newObjectServer(t, map[string][]byte{"slow.bin": content})
+	file := File{Key: "slow.bin", Size: len(content), Link: server.URL + "/slow.bin"}
+
+	// 1K/s 要等一分钟，取消后应该马上返回
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	results := (&Downloader{Dir: t.TempDir(), RateLimit: 1024}).DownloadContext(ctx, []File{file})
+	assert.ErrorIs(t, results[0].Err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
+
+func TestSafeLocalPath(t *testing.T) {
+	dir := t.TempDir()
+
+	p, err := SafeLocalPath(dir, "a/b/c.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "a", "b", "c.txt"), p)
+
+	p, err = SafeLocalPath(dir, "/etc//passwd")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "etc", "passwd"), p)
+
+	p, err = SafeLocalPath(dir, "a/b:c?.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "a", "b_c_.txt"), p)
+
+	for _, key := range []string{"../evil", "a/../../evil", `..\evil`, "a/./../../b", "", "a\x00b"} {
+		_, err := SafeLocalPath(dir, key)
+		assert.Error(t, err, key)
+	}
+}
+
+func TestDownloadContextCancelled(t *testing.T) {
+	server := newObjectServer(t, map[string][]byte{"a.txt": []byte("aaa"), "b.txt": []byte("bbb")})
+	files := []File{
+		{Key: "a.txt", Size: 3, Link: server.URL + "/a.txt"},
+		{Key: "b.txt", Size: 3, Link: server.URL + "/b.txt"},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results := (&Downloader{Dir: t.TempDir(), Workers: 1}).DownloadContext(ctx, files)
+	assert.Len(t, results, 2)
+	for _, r := range results {
+		assert.Equal(t, DownloadFailed, r.Status, r.Key)
+		assert.ErrorIs(t, r.Err, context.Canceled, r.Key)
+	}
+	assert.Equal(t, int64(0), server.requests.Load())
+}
@@ -0,0 +1,196 @@
+package s3viewer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// BucketRef 从文本里找到的一个 bucket 引用
+type BucketRef struct {
+	Provider string // aws、aliyun、tencent、gcp、azure
+	Bucket   string // Azure 为 account/container
+	Region   string `json:",omitempty"`
+	Url      string // 规范化后的列目录 URL，地域未知、拼不出来时为空
+	Match    string // 原文里匹配到的部分
+}
+
+// bucketPattern 一种写法对应一个正则；build 根据子匹配生成 BucketRef
+type bucketPattern struct {
+	re    *regexp.Regexp
+	build func(m []string) BucketRef
+}
+
+// 主机名前面不能紧挨着字母、数字、点、横线，避免 bucket.s3.amazonaws.com 被路径风格的规则再匹配一次
+const hostBoundary = `(?:^|[^a-z0-9.-])`
+
+const (
+	awsRegion = `([a-z]{2}(?:-gov|-iso)?-[a-z]+-\d)`
+	bucketDNS = `([a-z0-9][a-z0-9.-]{1,61}[a-z0-9])`
+)
+
+var bucketPatterns = []bucketPattern{
+	// AWS 虚拟主机风格：bucket.s3.amazonaws.com、bucket.s3.ap-east-1.amazonaws.com、bucket.s3-us-west-2.amazonaws.com、
+	// bucket.s3-accelerate.amazonaws.com、bucket.s3-website-us-east-1.amazonaws.com、bucket.s3.cn-north-1.amazonaws.com.cn
+	{regexp.MustCompile(hostBoundary + bucketDNS + `\.s3(?:-accelerate|-website)?(?:[.-](?:dualstack\.)?` + awsRegion + `)?\.amazonaws\.com(\.cn)?`),
+		func(m []string) BucketRef { return awsBucket(m[1], m[2], m[3] != "") }},
+	// AWS 路径风格：s3.amazonaws.com/bucket、s3.eu-west-1.amazonaws.com/bucket
+	{regexp.MustCompile(hostBoundary + `s3(?:[.-](?:dualstack\.)?` + awsRegion + `)?\.amazonaws\.com(\.cn)?/` + bucketDNS),
+		func(m []string) BucketRef { return awsBucket(m[3], m[1], m[2] != "") }},
+	{regexp.MustCompile(`\bs3a?://` + bucketDNS),
+		func(m []string) BucketRef { return awsBucket(m[1], "", false) }},
+
+	// 阿里云：bucket.oss-cn-hangzhou.aliyuncs.com、bucket.oss-cn-hangzhou-internal.aliyuncs.com、bucket.oss-accelerate.aliyuncs.com
+	{regexp.MustCompile(hostBoundary + `([a-z0-9][a-z0-9-]{1,61}[a-z0-9])\.oss(?:-([a-z0-9-]+?))?(?:-internal)?\.aliyuncs\.com`),
+		func(m []string) BucketRef { return ossBucket(m[1], m[2]) }},
+	{regexp.MustCompile(`\boss://([a-z0-9][a-z0-9-]{1,61}[a-z0-9])`),
+		func(m []string) BucketRef { return ossBucket(m[1], "") }},
+
+	// 腾讯云：bucket-1250000000.cos.ap-guangzhou.myqcloud.com、CDN 域名 bucket-1250000000.file.myqcloud.com
+	{regexp.MustCompile(hostBoundary + `([a-z0-9][a-z0-9-]*-\d{5,})\.cos(?:-website)?\.([a-z0-9-]+)\.myqcloud\.com`),
+		func(m []string) BucketRef { return cosBucket(m[1], m[2]) }},
+	{regexp.MustCompile(hostBoundary + `([a-z0-9][a-z0-9-]*-\d{5,})\.file\.myqcloud\.com`),
+		func(m []string) BucketRef { return cosBucket(m[1], "") }},
+	{regexp.MustCompile(`\bcos://([a-z0-9][a-z0-9-]*-\d{5,})`),
+		func(m []string) BucketRef { return cosBucket(m[1], "") }},
+
+	// Google：storage.googleapis.com/bucket、bucket.storage.googleapis.com、gs://bucket
+	{regexp.MustCompile(hostBoundary + `storage\.(?:googleapis|cloud\.google)\.com/([a-z0-9][a-z0-9._-]{1,220}[a-z0-9])`),
+		func(m []string) BucketRef { return gcsBucket(m[1]) }},
+	{regexp.MustCompile(hostBoundary + `([a-z0-9][a-z0-9._-]{1,220}[a-z0-9])\.storage\.googleapis\.com`),
+		func(m []string) BucketRef { return gcsBucket(m[1]) }},
+	{regexp.MustCompile(`\bgs://([a-z0-9][a-z0-9._-]{1,220}[a-z0-9])`),
+		func(m []string) BucketRef { return gcsBucket(m[1]) }},
+
+	// Azure：account.blob.core.windows.net/container、wasbs://container@account.blob.core.windows.net
+	{regexp.MustCompile(`\bwasbs?://([a-z0-9][a-z0-9-]{1,61}[a-z0-9])@([a-z0-9]{3,24})\.blob\.core\.windows\.net`),
+		func(m []string) BucketRef { return azureBucket(m[2], m[1]) }},
+	{regexp.MustCompile(hostBoundary + `([a-z0-9]{3,24})\.blob\.core\.windows\.net(?:/([a-z0-9][a-z0-9-]{1,61}[a-z0-9]|\$web))?`),
+		func(m []string) BucketRef { return azureBucket(m[1], m[2]) }},
+}
+
+func awsBucket(bucket string, region string, china bool) BucketRef {
+	ref := BucketRef{Provider: "aws", Bucket: bucket, Region: region}
+	domain := "amazonaws.com"
+	if china {
+		domain = "amazonaws.com.cn"
+	}
+	endpoint := "s3." + domain
+	if region != "" {
+		endpoint = "s3." + region + "." + domain
+	}
+	if strings.Contains(bucket, ".") {
+		// 带点的 bucket 名用虚拟主机风格时证书对不上，改用路径风格
+		ref.Url = "https://" + endpoint + "/" + bucket + "/"
+	} else {
+		ref.Url = "https://" + bucket + "." + endpoint + "/"
+	}
+	return ref
+}
+
+func ossBucket(bucket string, region string) BucketRef {
+	ref := BucketRef{Provider: "aliyun", Bucket: bucket}
+	if strings.HasPrefix(region, "accelerate") {
+		region = ""
+	}
+	ref.Region = region
+	if region == "" {
+		// oss.aliyuncs.com 指向杭州，其他地域会返回带正确 Endpoint 的错误
+		ref.Url = "https://" + bucket + ".oss.aliyuncs.com/"
+	} else {
+		ref.Url = "https://" + bucket + ".oss-" + region + ".aliyuncs.com/"
+	}
+	return ref
+}
+
+func cosBucket(bucket string, region string) BucketRef {
+	ref := BucketRef{Provider: "tencent", Bucket: bucket}
+	if region == "accelerate" {
+		region = ""
+	}
+	ref.Region = region
+	if region != "" {
+		ref.Url = "https://" + bucket + ".cos." + region + ".myqcloud.com/"
+	}
+	return ref
+}
+
+func gcsBucket(bucket string) BucketRef {
+	return BucketRef{Provider: "gcp", Bucket: bucket, Url: "https://storage.googleapis.com/" + bucket + "/"}
+}
+
+func azureBucket(account string, container string) BucketRef {
+	ref := BucketRef{Provider: "azure", Bucket: account}
+	if container == "" {
+		ref.Url = "https://" + account + ".blob.core.windows.net/?comp=list"
+		return ref
+	}
+	ref.Bucket = account + "/" + container
+	ref.Url = "https://" + account + ".blob.core.windows.net/" + container + "?restype=container&comp=list"
+	return ref
+}
+
+// jsEscapes JS、JSON 里转义过的斜杠
+var jsEscapes = strings.NewReplacer(`\/`, "/", `\u002F`, "/", `\u002f`, "/", "%2F", "/", "%2f", "/")
+
+// ExtractBuckets 在文本（HTML、JS、日志、APK 里的字符串等）里查找 bucket 引用，按 Provider、Bucket 去重排序
+func ExtractBuckets(text string) []BucketRef {
+	text = strings.ToLower(jsEscapes.Replace(text))
+	seen := make(map[string]int)
+	var refs []BucketRef
+	for _, p := range bucketPatterns {
+		for _, m := range p.re.FindAllStringSubmatch(text, -1) {
+			ref := p.build(m)
+			ref.Match = strings.TrimLeft(m[0], "\"'()<>=:,;` \t\r\n/\\")
+			key := ref.Provider + "|" + ref.Bucket
+			if i, ok := seen[key]; ok {
+				// 同一个 bucket 出现多次时，保留带地域的那个
+				if refs[i].Region == "" && ref.Region != "" {
+					refs[i] = ref
+				}
+				continue
+			}
+			seen[key] = len(refs)
+			refs = append(refs, ref)
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].Provider != refs[j].Provider {
+			return refs[i].Provider < refs[j].Provider
+		}
+		return refs[i].Bucket < refs[j].Bucket
+	})
+	return refs
+}
+
+// ReadExtractSource 读取要查找的内容：http(s):// 开头的下载下来，其余当作本地文件，"-" 表示 stdin
+func ReadExtractSource(source string, stdin io.Reader) (string, error) {
+	return ReadExtractSourceContext(context.Background(), source, stdin)
+}
+
+// ReadExtractSourceContext 同 ReadExtractSource，ctx 取消时下载随之中断
+func ReadExtractSourceContext(ctx context.Context, source string, stdin io.Reader) (string, error) {
+	if source == "-" {
+		data, err := io.ReadAll(stdin)
+		return string(data), err
+	}
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		resp, err := HttpGetContext(ctx, source)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("GET: %v", resp.Status)
+		}
+		data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<20))
+		return string(data), err
+	}
+	data, err := os.ReadFile(source)
+	return string(data), err
+}
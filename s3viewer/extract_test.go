@@ -0,0 +1,48 @@
+package s3viewer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestExtractBuckets(t *testing.T) {
+	text := `
+<img src="https://acme-static.s3.ap-east-1.amazonaws.com/logo.png">
+<script>var cfg={"upload":"https:\/\/s3.eu-west-1.amazonaws.com\/acme.backup\/","cdn":"//Acme-Static.s3.amazonaws.com/"};</script>
+2024-06-01 INFO sync s3://acme-logs/2024/ done
+oss: https://acme-oss.oss-cn-hangzhou-internal.aliyuncs.com/a.jpg oss://acme-private
+cos: https://acme-1250000000.cos.ap-guangzhou.myqcloud.com/x and https://acme-1250000001.file.myqcloud.com/y
+gcs: https://storage.googleapis.com/acme-gcs/file gs://acme-data
+azure: https://acmeblob.blob.core.windows.net/images/a.png
+nothing here: https://www.example.com/s3.amazonaws.com.html
+`
+	refs := ExtractBuckets(text)
+	byBucket := make(map[string]BucketRef)
+	for _, ref := range refs {
+		byBucket[ref.Bucket] = ref
+	}
+	assert.Len(t, refs, 10)
+
+	// 同一个 bucket 出现多次，保留带地域的
+	assert.Equal(t, BucketRef{Provider: "aws", Bucket: "acme-static", Region: "ap-east-1",
+		Url: "https://acme-static.s3.ap-east-1.amazonaws.com/", Match: "acme-static.s3.ap-east-1.amazonaws.com"}, byBucket["acme-static"])
+	// 带点的用路径风格
+	assert.Equal(t, "https://s3.eu-west-1.amazonaws.com/acme.backup/", byBucket["acme.backup"].Url)
+	assert.Equal(t, "https://acme-logs.s3.amazonaws.com/", byBucket["acme-logs"].Url)
+
+	assert.Equal(t, "cn-hangzhou", byBucket["acme-oss"].Region)
+	assert.Equal(t, "https://acme-oss.oss-cn-hangzhou.aliyuncs.com/", byBucket["acme-oss"].Url)
+	assert.Equal(t, "https://acme-private.oss.aliyuncs.com/", byBucket["acme-private"].Url)
+
+	assert.Equal(t, "https://acme-1250000000.cos.ap-guangzhou.myqcloud.com/", byBucket["acme-1250000000"].Url)
+	assert.Equal(t, "tencent", byBucket["acme-1250000001"].Provider)
+	assert.Empty(t, byBucket["acme-1250000001"].Url)
+
+	assert.Equal(t, "https://storage.googleapis.com/acme-gcs/", byBucket["acme-gcs"].Url)
+	assert.Equal(t, "gcp", byBucket["acme-data"].Provider)
+	assert.Equal(t, "https://acmeblob.blob.core.windows.net/images?restype=container&comp=list", byBucket["acmeblob/images"].Url)
+}
+
+func TestExtractBucketsNoMatch(t *testing.T) {
+	assert.Empty(t, ExtractBuckets("see https://aws.amazon.com/s3/ and https://www.aliyun.com/product/oss"))
+}
@@ -0,0 +1,253 @@
+package s3viewer
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Filter 在爬取和输出之间过滤文件，零值表示不过滤。第一次 Matches 时编译 glob，之后不要按值复制，传 *Filter
+type Filter struct {
+	Include []string       // glob，命中任意一个才保留；不含 / 的 glob 同时匹配文件名
+	Exclude []string       // glob，命中任意一个就丢弃
+	Match   *regexp.Regexp // 对 Key 做正则匹配
+	Exts    []string       // 扩展名，例如 pdf、xlsx、tar.gz
+	MinSize int64          // 最小字节数，0 表示不限制
+	MaxSize int64          // 最大字节数，0 表示不限制
+	Since   time.Time      // LastModified 下限，零值表示不限制；设置了上下限时没有 LastModified 的对象一律丢弃
+	Until   time.Time      // LastModified 上限，零值表示不限制
+	NoDirs  bool           // 丢弃 `folder/` 这种 0 字节的目录占位对象
+
+	once     sync.Once
+	include  []glob
+	exclude  []glob
+	exts     []string
+	compiled error
+}
+
+// Compile 预编译 glob，参数有误时返回错误；Matches 会自动调用
+func (f *Filter) Compile() error {
+	if f == nil {
+		return nil
+	}
+	f.once.Do(func() {
+		if f.include, f.compiled = compileGlobs(f.Include); f.compiled != nil {
+			return
+		}
+		if f.exclude, f.compiled = compileGlobs(f.Exclude); f.compiled != nil {
+			return
+		}
+		for _, ext := range f.Exts {
+			ext = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), "."))
+			if ext != "" {
+				f.exts = append(f.exts, "."+ext)
+			}
+		}
+	})
+	return f.compiled
+}
+
+// Matches 判断文件是否满足所有过滤条件，nil Filter 永远返回 true
+func (f *Filter) Matches(file File) bool {
+	if f == nil {
+		return true
+	}
+	if f.Compile() != nil {
+		return false
+	}
+
+	if f.NoDirs && file.Size == 0 && strings.HasSuffix(file.Key, "/") {
+		return false
+	}
+	if len(f.include) > 0 && !matchAnyGlob(f.include, file.Key) {
+		return false
+	}
+	if matchAnyGlob(f.exclude, file.Key) {
+		return false
+	}
+	if f.Match != nil && !f.Match.MatchString(file.Key) {
+		return false
+	}
+	if len(f.exts) > 0 {
+		key := strings.ToLower(file.Key)
+		ok := false
+		for _, ext := range f.exts {
+			if strings.HasSuffix(key, ext) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	if f.MinSize > 0 && int64(file.Size) < f.MinSize {
+		return false
+	}
+	if f.MaxSize > 0 && int64(file.Size) > f.MaxSize {
+		return false
+	}
+	if (!f.Since.IsZero() || !f.Until.IsZero()) && file.LastModified.IsZero() {
+		return false
+	}
+	if !f.Since.IsZero() && file.LastModified.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && file.LastModified.After(f.Until) {
+		return false
+	}
+	return true
+}
+
+// Apply 返回满足条件的文件，不修改原切片
+func (f *Filter) Apply(files []File) []File {
+	if f == nil {
+		return files
+	}
+	kept := make([]File, 0, len(files))
+	for _, file := range files {
+		if f.Matches(file) {
+			kept = append(kept, file)
+		}
+	}
+	return kept
+}
+
+// FilterResult 过滤结果中的文件，返回新的 ListBucketResult
+func FilterResult(result *ListBucketResult, f *Filter) (*ListBucketResult, error) {
+	if err := f.Compile(); err != nil {
+		return nil, err
+	}
+	filtered := *result
+	filtered.Files = f.Apply(result.Files)
+	return &filtered, nil
+}
+
+// glob 编译好的 glob；不含 / 的同时匹配文件名
+type glob struct {
+	re   *regexp.Regexp
+	base bool
+}
+
+func compileGlobs(patterns []string) ([]glob, error) {
+	var globs []glob
+	for _, p := range patterns {
+		re, err := globToRegexp(p)
+		if err != nil {
+			return nil, err
+		}
+		globs = append(globs, glob{re: re, base: !strings.Contains(p, "/")})
+	}
+	return globs, nil
+}
+
+// matchAnyGlob key 命中任意一个 glob；不含 / 的 glob 也拿文件名去匹配，含 / 的只匹配完整的 Key
+func matchAnyGlob(globs []glob, key string) bool {
+	base := path.Base(key)
+	for _, g := range globs {
+		if g.re.MatchString(key) || g.base && g.re.MatchString(base) {
+			return true
+		}
+	}
+	return false
+}
+
+// globToRegexp 把 glob 转成正则：`*` 不跨目录，`**` 可以跨目录，`?` 匹配单个字符
+func globToRegexp(glob string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("^")
+	runes := []rune(glob)
+	for i := 0; i < len(runes); i++ {
+		switch c := runes[i]; c {
+		case '*':
+			if i+1 < len(runes) && runes[i+1] == '*' {
+				sb.WriteString(".*")
+				i++
+			} else {
+				sb.WriteString("[^/]*")
+			}
+		case '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+	re, err := regexp.Compile(sb.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob %q: %w", glob, err)
+	}
+	return re, nil
+}
+
+// ParseSize 解析带单位的大小，例如 100、512K、10MB、1.5GiB，按 1024 进位
+func ParseSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	i := 0
+	for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.') {
+		i++
+	}
+	n, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size: %v", s)
+	}
+
+	var multiplier float64
+	switch strings.ToUpper(strings.TrimSpace(s[i:])) {
+	case "", "B":
+		multiplier = 1
+	case "K", "KB", "KIB":
+		multiplier = 1 << 10
+	case "M", "MB", "MIB":
+		multiplier = 1 << 20
+	case "G", "GB", "GIB":
+		multiplier = 1 << 30
+	case "T", "TB", "TIB":
+		multiplier = 1 << 40
+	default:
+		return 0, fmt.Errorf("invalid size unit: %v", s)
+	}
+	return int64(n * multiplier), nil
+}
+
+// ParseTimeArg 解析 -since/-until：支持 2006-01-02、RFC3339，以及相对时间 7d、12h（相对于 now 往前推）
+func ParseTimeArg(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if strings.HasSuffix(s, "d") {
+		if days, err := strconv.Atoi(strings.TrimSuffix(s, "d")); err == nil {
+			return now.AddDate(0, 0, -days), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time: %v", s)
+}
+
+// ParseUntilArg 同 ParseTimeArg，用于上限：只写日期时包含这一整天，返回当天最后一纳秒
+func ParseUntilArg(s string, now time.Time) (time.Time, error) {
+	t, err := ParseTimeArg(s, now)
+	if err != nil {
+		return t, err
+	}
+	if _, dateErr := time.Parse("2006-01-02", strings.TrimSpace(s)); dateErr == nil {
+		t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	return t, nil
+}
@@ -0,0 +1,116 @@
+package s3viewer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func filterKeys(f *Filter, files []File) []string {
+	var keys []string
+	for _, file := range f.Apply(files) {
+		keys = append(keys, file.Key)
+	}
+	return keys
+}
+
+func TestFilterNoDirs(t *testing.T) {
+	result, err := LoadFile("../test/h1.xml")
+	if err != nil {
+		t.Fatalf("Failed to load file: %v", err)
+	}
+	assert.Equal(t, "book/", result.Files[0].Key)
+
+	filtered := (&Filter{NoDirs: true}).Apply(result.Files)
+	assert.Len(t, filtered, len(result.Files)-1)
+	assert.NotEqual(t, "book/", filtered[0].Key)
+}
+
+func TestFilterMatches(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	files := []File{
+		{Key: "db/backup.sql", Size: 10 << 20, LastModified: day(1)},
+		{Key: "db/old/backup.tar.gz", Size: 1 << 30, LastModified: day(2)},
+		{Key: "docs/report.PDF", Size: 2048, LastModified: day(3)},
+		{Key: "docs/", Size: 0, LastModified: day(4)},
+		{Key: "index.html", Size: 512, LastModified: day(5)},
+	}
+
+	assert.Equal(t, []string{"db/backup.sql"}, filterKeys(&Filter{Include: []string{"db/*"}}, files))
+	assert.Equal(t, []string{"db/backup.sql", "db/old/backup.tar.gz"}, filterKeys(&Filter{Include: []string{"db/**"}}, files))
+	// 不含 / 的 glob 同时匹配文件名
+	assert.Equal(t, []string{"db/backup.sql", "db/old/backup.tar.gz"}, filterKeys(&Filter{Include: []string{"backup.*"}}, files))
+	// 含 / 的 glob 只匹配完整的 Key，不从中间开始匹配
+	assert.Empty(t, filterKeys(&Filter{Include: []string{"old/*"}}, files))
+	assert.Equal(t, []string{"db/old/backup.tar.gz"}, filterKeys(&Filter{Include: []string{"*/old/*"}}, files))
+	assert.Equal(t, []string{"docs/report.PDF", "docs/", "index.html"}, filterKeys(&Filter{Exclude: []string{"db/**"}}, files))
+	assert.Equal(t, []string{"docs/report.PDF"}, filterKeys(&Filter{Match: regexp.MustCompile(`report`)}, files))
+	assert.Equal(t, []string{"db/old/backup.tar.gz", "docs/report.PDF"}, filterKeys(&Filter{Exts: []string{"pdf", ".tar.gz"}}, files))
+	assert.Equal(t, []string{"db/backup.sql", "docs/report.PDF"}, filterKeys(&Filter{MinSize: 1024, MaxSize: 100 << 20}, files))
+	assert.Equal(t, []string{"db/old/backup.tar.gz", "docs/report.PDF"}, filterKeys(&Filter{Since: day(2), Until: day(3)}, files))
+	// 没有 LastModified 的对象，只设置上限或者只设置下限都丢弃
+	undated := File{Key: "undated.txt", Size: 1}
+	assert.False(t, (&Filter{Since: day(2)}).Matches(undated))
+	assert.False(t, (&Filter{Until: day(3)}).Matches(undated))
+	assert.True(t, (&Filter{}).Matches(undated))
+
+	var nilFilter *Filter
+	assert.Len(t, nilFilter.Apply(files), len(files))
+}
+
+func TestParseSize(t *testing.T) {
+	cases := map[string]int64{
+		"":       0,
+		"100":    100,
+		"512K":   512 << 10,
+		"10MB":   10 << 20,
+		"1.5GiB": 3 << 29,
+	}
+	for s, want := range cases {
+		got, err := ParseSize(s)
+		assert.NoError(t, err, s)
+		assert.Equal(t, want, got, s)
+	}
+
+	_, err := ParseSize("10XB")
+	assert.Error(t, err)
+}
+
+func TestParseTimeArg(t *testing.T) {
+	now := time.Date(2024, 6, 23, 12, 0, 0, 0, time.UTC)
+
+	got, err := ParseTimeArg("2024-01-02", now)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), got)
+
+	got, err = ParseTimeArg("7d", now)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 6, 16, 12, 0, 0, 0, time.UTC), got)
+
+	got, err = ParseTimeArg("12h", now)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 6, 23, 0, 0, 0, 0, time.UTC), got)
+
+	_, err = ParseTimeArg("yesterday", now)
+	assert.Error(t, err)
+}
+
+func TestParseUntilArg(t *testing.T) {
+	now := time.Date(2024, 6, 23, 12, 0, 0, 0, time.UTC)
+
+	// 只写日期时包含这一整天
+	until, err := ParseUntilArg("2024-01-02", now)
+	assert.NoError(t, err)
+	filter := &Filter{Until: until}
+	assert.True(t, filter.Matches(File{Key: "a", LastModified: time.Date(2024, 1, 2, 18, 30, 0, 0, time.UTC)}))
+	assert.False(t, filter.Matches(File{Key: "a", LastModified: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)}))
+
+	until, err = ParseUntilArg("2024-01-02T08:00:00Z", now)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 1, 2, 8, 0, 0, 0, time.UTC), until)
+
+	until, err = ParseUntilArg("12h", now)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 6, 23, 0, 0, 0, 0, time.UTC), until)
+}
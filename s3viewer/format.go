@@ -0,0 +1,191 @@
+package s3viewer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TimeLayout 输出时间时使用的格式，和 S3 返回的 LastModified 保持一致
+const TimeLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// 可选的列名
+const (
+	ColumnKey          = "Key"
+	ColumnSize         = "Size"
+	ColumnLastModified = "LastModified"
+	ColumnLink         = "Link"
+	ColumnETag         = "ETag"
+	ColumnContentType  = "ContentType"
+	ColumnDetectedType = "DetectedType"
+	ColumnTypeMismatch = "TypeMismatch"
+	ColumnCategory     = "Category"
+	ColumnSeverity     = "Severity"
+)
+
+// DefaultColumns 不指定 -columns 时打印的列
+var DefaultColumns = []string{ColumnKey, ColumnSize, ColumnLastModified}
+
+// 列名 -> 表头
+var columnTitles = map[string]string{
+	ColumnKey:          "Key",
+	ColumnSize:         "Size",
+	ColumnLastModified: "LastModifiedDate",
+	ColumnLink:         "Link",
+	ColumnETag:         "ETag",
+	ColumnContentType:  "ContentType",
+	ColumnDetectedType: "DetectedType",
+	ColumnTypeMismatch: "TypeMismatch",
+	ColumnCategory:     "Category",
+	ColumnSeverity:     "Severity",
+}
+
+// 列名的别名，方便命令行输入
+var columnAliases = map[string]string{
+	"key":          ColumnKey,
+	"size":         ColumnSize,
+	"lastmodified": ColumnLastModified,
+	"date":         ColumnLastModified,
+	"link":         ColumnLink,
+	"url":          ColumnLink,
+	"etag":         ColumnETag,
+	"contenttype":  ColumnContentType,
+	"detectedtype": ColumnDetectedType,
+	"detected":     ColumnDetectedType,
+	"typemismatch": ColumnTypeMismatch,
+	"mismatch":     ColumnTypeMismatch,
+	"category":     ColumnCategory,
+	"severity":     ColumnSeverity,
+}
+
+// PrintOptions 控制 PrintResult 的输出
+type PrintOptions struct {
+	Columns []string // 要打印的列，为空时使用 DefaultColumns
+	Human   bool     // Size 是否换算成 KiB/MiB/GiB
+}
+
+// ParseColumns 解析逗号分隔的列名，例如 "key,size,link"，大小写不敏感
+func ParseColumns(s string) ([]string, error) {
+	var columns []string
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		c, ok := columnAliases[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown column: %v", name)
+		}
+		columns = append(columns, c)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no columns specified")
+	}
+	return columns, nil
+}
+
+func formatColumn(file File, column string, opts PrintOptions) string {
+	switch column {
+	case ColumnKey:
+		return file.Key
+	case ColumnSize:
+		if opts.Human {
+			return HumanSize(int64(file.Size))
+		}
+		return fmt.Sprint(file.Size)
+	case ColumnLastModified:
+		return FormatTime(file.LastModified)
+	case ColumnLink:
+		return file.Link
+	case ColumnETag:
+		return file.ETag
+	case ColumnContentType:
+		return file.ContentType
+	case ColumnDetectedType:
+		return file.DetectedType
+	case ColumnTypeMismatch:
+		return formatBool(file.TypeMismatch)
+	case ColumnCategory:
+		return file.Category
+	case ColumnSeverity:
+		return file.Severity
+	}
+	return ""
+}
+
+// formatBool true 输出 "true"，false 输出空字符串，表格里更醒目
+func formatBool(b bool) string {
+	if b {
+		return "true"
+	}
+	return ""
+}
+
+// HumanSize 把字节数换算成合适的单位，例如 1536 -> 1.5 KiB
+func HumanSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit && exp < 4; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTP"[exp])
+}
+
+// ParseTime 解析 LastModified，兼容几种常见的时间格式
+func ParseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	layouts := []string{
+		time.RFC3339Nano,
+		"2006-01-02T15:04:05.000Z",
+		time.RFC1123,
+		time.RFC1123Z,
+		"2006-01-02 15:04:05",
+	}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unsupported time format: %v", s)
+}
+
+// FormatTime 把时间格式化为 TimeLayout，零值返回空字符串
+func FormatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(TimeLayout)
+}
+
+// SortFiles 按 key|size|date 对文件排序，reverse 为 true 时倒序（例如最新的在最前面）
+func SortFiles(files []File, by string, reverse bool) error {
+	var less func(a, b File) bool
+	switch strings.ToLower(by) {
+	case "":
+		return nil
+	case "key", "name":
+		less = func(a, b File) bool { return a.Key < b.Key }
+	case "size":
+		less = func(a, b File) bool { return a.Size < b.Size }
+	case "date", "time", "lastmodified":
+		less = func(a, b File) bool { return a.LastModified.Before(b.LastModified) }
+	default:
+		return fmt.Errorf("unknown sort field: %v", by)
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		if reverse {
+			return less(files[j], files[i])
+		}
+		return less(files[i], files[j])
+	})
+	return nil
+}
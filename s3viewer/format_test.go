@@ -0,0 +1,76 @@
+package s3viewer
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHumanSize(t *testing.T) {
+	assert.Equal(t, "0 B", HumanSize(0))
+	assert.Equal(t, "1023 B", HumanSize(1023))
+	assert.Equal(t, "1.5 KiB", HumanSize(1536))
+	assert.Equal(t, "5.5 MiB", HumanSize(5803443))
+	assert.Equal(t, "2.0 GiB", HumanSize(2<<30))
+}
+
+func TestParseXMLTime(t *testing.T) {
+	result, err := parseXMLToListBucketResult(sanitizeXMLContent([]byte(sampleXML)))
+	if err != nil {
+		t.Fatalf("Failed to parse XML to ListBucketResult: %v", err)
+	}
+
+	file := result.Files[0]
+	assert.Equal(t, time.Date(2023, 5, 22, 8, 49, 7, 0, time.UTC), file.LastModified)
+	assert.Equal(t, "f19cd76cd7fac68d15f0c40a063519c9", file.ETag)
+	assert.Equal(t, "2023-05-22T08:49:07.000Z", FormatTime(file.LastModified))
+}
+
+func TestSortFiles(t *testing.T) {
+	files := []File{
+		{Key: "b", Size: 3, LastModified: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Key: "a", Size: 1, LastModified: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Key: "c", Size: 2, LastModified: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	keys := func() string {
+		var s []string
+		for _, f := range files {
+			s = append(s, f.Key)
+		}
+		return strings.Join(s, "")
+	}
+
+	assert.NoError(t, SortFiles(files, "key", false))
+	assert.Equal(t, "abc", keys())
+	assert.NoError(t, SortFiles(files, "size", true))
+	assert.Equal(t, "bca", keys())
+	// 最新的在最前面
+	assert.NoError(t, SortFiles(files, "date", true))
+	assert.Equal(t, "acb", keys())
+	assert.Error(t, SortFiles(files, "owner", false))
+}
+
+func TestPrintResultColumns(t *testing.T) {
+	result := &ListBucketResult{Files: []File{
+		{Key: "a.zip", Size: 2048, ETag: "etag-a", Link: "http://s3.example.com/a.zip"},
+	}}
+	columns, err := ParseColumns("key,size,link,etag")
+	if err != nil {
+		t.Fatalf("Failed to parse columns: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := PrintResult(&buf, result, PrintOptions{Columns: columns, Human: true}); err != nil {
+		t.Fatalf("Failed to print result: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.Equal(t, []string{"Key", "Size", "Link", "ETag"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"a.zip", "2.0", "KiB", "http://s3.example.com/a.zip", "etag-a"}, strings.Fields(lines[1]))
+
+	_, err = ParseColumns("key,owner")
+	assert.Error(t, err)
+}
@@ -0,0 +1,263 @@
+package s3viewer
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// 支持的语言
+const (
+	LangEN = "en"
+	LangZH = "zh"
+)
+
+var lang atomic.Value
+
+// SetLang 设置日志、错误、命令行帮助和 web 页面使用的语言，en 或 zh
+func SetLang(l string) error {
+	switch l = strings.ToLower(l); l {
+	case LangEN, LangZH:
+		lang.Store(l)
+		return nil
+	}
+	return fmt.Errorf("unsupported language: %v", l)
+}
+
+// Lang 当前语言，没有设置过时为 en
+func Lang() string {
+	if l, ok := lang.Load().(string); ok {
+		return l
+	}
+	return LangEN
+}
+
+// DetectLang 按 LC_ALL、LC_MESSAGES、LANG 的顺序判断语言，zh_CN.UTF-8 之类的为 zh，其余为 en
+func DetectLang() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if v := os.Getenv(name); v != "" {
+			if strings.HasPrefix(strings.ToLower(v), "zh") {
+				return LangZH
+			}
+			return LangEN
+		}
+	}
+	return LangEN
+}
+
+// T 翻译一条消息：msg 是英文原文（也是 catalog 的 key），有 args 时按 fmt 格式化；
+// 没有翻译的消息原样使用英文
+func T(msg string, args ...any) string {
+	if Lang() == LangZH {
+		if zh, ok := zhMessages[msg]; ok {
+			msg = zh
+		}
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+// zhMessages 英文 -> 中文，格式化动词（%v）的个数和顺序必须一致
+var zhMessages = map[string]string{
+	// 库里的日志
+	"Range request failed, reading the head instead":   "Range 请求失败，改为只读开头",
+	"Range request failed, downloading the whole file": "Range 请求失败，改为整个下载",
+	"http get":                          "请求",
+	"next page":                         "尝试请求下一页",
+	"page fetched":                      "拉取到一页",
+	"last page":                         "不必翻页，本页已经返回了全部结果",
+	"partial page":                      "本页只解析出一部分",
+	"cannot fetch next page":            "翻页失败",
+	"listing done":                      "结果统计",
+	"listing interrupted":               "爬取中断",
+	"failed to fill links":              "拼接下载链接失败",
+	"invalid LastModified":              "LastModified 格式不对",
+	"failed to create output directory": "创建输出目录失败",
+	"failed to crawl":                   "爬取失败",
+	"download failed":                   "下载失败",
+	"verify failed":                     "校验失败",
+	"bucket found":                      "发现 bucket",
+	"scan failed":                       "扫描失败",
+	"sniff failed":                      "类型识别失败",
+	"failed to list archive":            "列出压缩包失败",
+	"archive read limit reached":        "压缩包读取到上限，停止",
+	"poll failed":                       "轮询失败",
+	"poll done":                         "轮询完成",
+	"webhook failed":                    "通知 webhook 失败",
+
+	// 库里的错误
+	"cannot page, the target does not seem to support paging":                      "[!]无法翻页，应该是不支持翻页",
+	"the next page URL is the same as the current one, a CDN ignoring parameters?": "[!]两次 URL完全相同，可能是目标部署了「忽略参数的CDN」，这种情况无法翻页",
+
+	// 命令行的日志
+	"Loaded %v objects from saved pages":                           "[+]从保存的页面读取: %v 条",
+	"Objects inside archives: %v":                                  "[+]压缩包内文件: %v 条",
+	"%v/%v objects left after filtering":                           "过滤后剩余 %v/%v 条",
+	"Sniffed %v objects, %v failed, %v with mismatched extension":  "[+]类型识别完成: %v 个对象, 失败 %v 个, 扩展名与内容不符 %v 个",
+	"Scanned %v objects, %v with findings":                         "[+]内容扫描完成: 扫描 %v 个对象, 命中 %v 个",
+	"Targets: %v":                                                  "[+]目标数: %v",
+	"Candidates: %v":                                               "[+]候选数: %v",
+	"Buckets found: %v":                                            "[+]找到 bucket: %v 个",
+	"Downloading %v objects into %v":                               "开始下载 %v 个对象到 %v",
+	"Download done: downloaded=%v resumed=%v skipped=%v failed=%v": "[+]下载完成: downloaded=%v resumed=%v skipped=%v failed=%v",
+	"Verify: ok=%v mismatch=%v unverified=%v":                      "[+]校验结果: ok=%v mismatch=%v unverified=%v",
+	"Interrupted, no new requests, saving what we have (Ctrl-C again to quit now)": "[!]收到中断信号，停止发起新的请求，保存已经拿到的结果（再按一次 Ctrl-C 直接退出）",
+	"Saved into %v":             "已保存到 %v",
+	"Saved %v objects":          "已写出 %v 条",
+	"Saved diff into %v":        "差异已保存到 %v",
+	"Saved summary into %v":     "汇总已保存到 %v",
+	"Saved scan report into %v": "扫描报告已保存到 %v",
+	"Saved report into %v":      "报告已保存到 %v",
+	"Failed to read %v: %v":     "读取 %v 失败: %v",
+	"Skipped %v Azure containers, their listing is not S3 XML": "跳过 %v 个 Azure 容器，它们的列表不是 S3 XML 格式",
+
+	// 命令行的错误
+	"s3 URL is required":                "缺少 s3 URL（-u）",
+	"Invalid -u: %v":                    "-u 不正确: %v",
+	"Invalid filter: %v":                "过滤参数不正确: %v",
+	"Invalid -columns: %v":              "-columns 不正确: %v",
+	"Invalid -sort: %v":                 "-sort 不正确: %v",
+	"Invalid -sniff: %v":                "-sniff 不正确: %v",
+	"Invalid -scan-max-size: %v":        "-scan-max-size 不正确: %v",
+	"Invalid -preview-max-size: %v":     "-preview-max-size 不正确: %v",
+	"Invalid -part-size: %v":            "-part-size 不正确: %v",
+	"Invalid -limit: %v":                "-limit 不正确: %v",
+	"Invalid -interval: %v":             "-interval 不正确: %v",
+	"Invalid -log-format: %v":           "-log-format 不正确: %v",
+	"Invalid -lang: %v":                 "-lang 不正确: %v",
+	"Invalid -t, {name} is missing: %v": "-t 不正确，缺少 {name}: %v",
+	"No targets in %v":                  "%v 里没有目标",
+	"Failed to load %v: %v":             "读取 %v 失败: %v",
+	"Failed to load -f: %v":             "读取 -f 失败: %v",
+	"Failed to load remote URL: %v":     "拉取列表失败: %v",
+	"Failed to load file list: %v":      "读取文件列表失败: %v",
+	"Failed to filter result: %v":       "过滤失败: %v",
+	"Failed to stream result: %v":       "边爬边写失败: %v",
+	"Failed to save result: %v":         "保存结果失败: %v",
+	"Failed to save summary: %v":        "保存汇总失败: %v",
+	"Failed to save scan report: %v":    "保存扫描报告失败: %v",
+	"Failed to save report: %v":         "保存报告失败: %v",
+	"Failed to save diff: %v":           "保存差异失败: %v",
+	"Failed to print summary: %v":       "打印汇总失败: %v",
+	"Failed to print scan report: %v":   "打印扫描报告失败: %v",
+	"Failed to print results: %v":       "打印结果失败: %v",
+	"Failed to print diff: %v":          "打印差异失败: %v",
+	"Failed to write results: %v":       "写出结果失败: %v",
+	"Failed to write events: %v":        "写出事件失败: %v",
+	"Failed to write diff: %v":          "写出差异失败: %v",
+	"Failed to write JSON: %v":          "写出 JSON 失败: %v",
+	"Failed to read wordlist: %v":       "读取词表失败: %v",
+	"Failed to open target list: %v":    "打开目标列表失败: %v",
+	"Failed to read target list: %v":    "读取目标列表失败: %v",
+	"Failed to create output file: %v":  "创建输出文件失败: %v",
+	"-stream can not be used with -f":   "-stream 不能和 -f 一起使用",
+	"-stream can not be used with -%v":  "-stream 不能和 -%v 一起使用",
+	"-sniff and -scan need downloadable objects, %v does not support them": "-sniff 和 -scan 需要能下载对象，%v 不支持",
+	"-archives needs Range requests, %v does not support them":             "-archives 需要 Range 请求，%v 不支持",
+
+	// 命令行帮助
+	"Usage:": "用法:",
+	"s3 URL, such as http://bucket.s3.amazonaws.com/, s3://bucket/prefix or oss://bucket":                                                                              "s3 地址，例如 http://bucket.s3.amazonaws.com/、s3://bucket/prefix 或 oss://bucket",
+	"s3 URL, such as http://bucket.s3.amazonaws.com/, s3://bucket/prefix or oss://bucket, or a saved XML/HTML listing file":                                            "s3 地址，例如 http://bucket.s3.amazonaws.com/、s3://bucket/prefix 或 oss://bucket，也可以是保存下来的 XML/HTML 列表页面",
+	"endpoint for s3:// oss:// cos:// URLs or path-style hosts":                                                                                                        "s3:// oss:// cos:// 地址或路径风格主机使用的 endpoint",
+	"endpoint for s3:// oss:// cos:// URLs or path-style hosts, e.g. http://127.0.0.1:9000":                                                                            "s3:// oss:// cos:// 地址或路径风格主机使用的 endpoint，例如 http://127.0.0.1:9000",
+	"output file, format by extension (.csv .json .jsonl .txt); can be repeated, - prints the table and format:path forces a format, e.g. -o - -o all.csv -o all.json": "输出文件，按扩展名选择格式（.csv .json .jsonl .txt）；可以重复，- 表示在终端打印表格，format:path 指定格式，例如 -o - -o all.csv -o all.json",
+	"saved listing page (XML or HTML) for offline analysis, can be a glob and repeated, e.g. -f 'pages/*.xml'; with -u the links are filled from it":                   "离线分析保存下来的列表页面（XML 或 HTML），支持 glob、可以重复，例如 -f 'pages/*.xml'；同时指定 -u 时用它拼下载链接",
+	"max page":                          "最多翻几页",
+	"max page per crawl":                "每次爬取最多翻几页",
+	"max page per bucket when crawling": "爬取时每个 bucket 最多翻几页",
+	"browse the result in a local web page, see -listen":                "在本地网页里浏览结果，地址见 -listen",
+	"largest object the web page previews through its proxy":            "网页通过代理预览的对象大小上限",
+	"address of the web page, implies -web":                             "网页的监听地址，设置后自动开启 -web",
+	"sort by key|size|date":                                             "排序：key|size|date",
+	"reverse the sort order, e.g. -sort date -reverse for newest first": "倒序，例如 -sort date -reverse 最新的在前",
+	"print sizes in KiB/MiB/GiB":                                        "大小显示为 KiB/MiB/GiB",
+	"columns to print: Key,Size,LastModified,Link,ETag,ContentType,DetectedType,TypeMismatch,Category,Severity": "打印的列：Key,Size,LastModified,Link,ETag,ContentType,DetectedType,TypeMismatch,Category,Severity",
+	"classify sensitive files by key (backups, credentials, PII, ...) and print a summary":                      "根据文件名判断敏感文件（备份、凭据、个人信息...）并打印汇总",
+	"YAML file with extra classification rules, implies -classify":                                              "追加分类规则的 YAML 文件，隐含 -classify",
+	"download documents (docx/xlsx/pptx/pdf/txt/csv/...) and scan their content for secrets and PII":            "下载文档（docx/xlsx/pptx/pdf/txt/csv/...），扫描内容里的密钥和个人信息",
+	"skip objects larger than this when scanning":                                                               "扫描时跳过超过这个大小的对象",
+	"save the scan report to this file (.csv or .json)":                                                         "扫描报告保存到这个文件（.csv 或 .json）",
+	"detect content types: head (HEAD only) or range (HEAD + first 512 bytes)":                                  "识别文件类型：head（只发 HEAD）或 range（HEAD 加上前 512 字节）",
+	"list files inside .zip/.tar/.tar.gz objects as archive.zip!/inner/path":                                    "列出 .zip/.tar/.tar.gz 里的文件，形如 archive.zip!/inner/path",
+	"max entries to list per archive":                                                                           "每个压缩包最多列出多少个文件",
+	"file with one target URL per line, or - for stdin (batch mode)":                                            "目标列表文件，每行一个 URL，- 表示 stdin（批量模式）",
+	"number of targets crawled at the same time in batch mode":                                                  "批量模式下同时爬取的目标数",
+	"max requests per second to the same host in batch mode, including every listing page (0 = unlimited)":      "批量模式下同一主机每秒最多发几个请求，列表的每一页都算（0 表示不限）",
+	"save each target's result into this directory in batch mode":                                               "批量模式下每个目标的结果保存到这个目录",
+	"format of the per-target results: csv or json":                                                             "每个目标结果的格式：csv 或 json",
+	"save the batch summary to this file (.csv or .json)":                                                       "批量汇总保存到这个文件（.csv 或 .json）",
+	"previous export (.csv or .json) to diff this crawl against":                                                "和之前导出的结果（.csv 或 .json）比较",
+	"save the -compare-with diff to this file (.json, .html or table text)":                                     "-compare-with 的差异保存到这个文件（.json、.html 或文本表格）",
+	"write -o (.csv or .jsonl) page by page while crawling instead of keeping every page in memory":             "边爬边写 -o（.csv 或 .jsonl），不把所有页都放在内存里",
+	"verbose, also log every request and page":                                                                  "详细日志，记录每个请求和每一页",
+	"quiet, hide info logs":                              "安静模式，只记录警告和错误",
+	"log format: text or json":                           "日志格式：text 或 json",
+	"language of messages: en or zh (default from LANG)": "消息语言：en 或 zh（默认根据 LANG）",
+	"only keep keys matching the glob, can be repeated, e.g. '*.pdf' or 'backup/**'":           "只保留匹配 glob 的 Key，可以重复，例如 '*.pdf' 或 'backup/**'",
+	"drop keys matching the glob, can be repeated":                                             "丢弃匹配 glob 的 Key，可以重复",
+	"only keep keys matching the regex":                                                        "只保留匹配正则的 Key",
+	"only keep these extensions, e.g. pdf,xlsx,sql":                                            "只保留这些扩展名，例如 pdf,xlsx,sql",
+	"minimum object size, e.g. 10K, 1.5MB":                                                     "最小大小，例如 10K、1.5MB",
+	"maximum object size, e.g. 100MB":                                                          "最大大小，例如 100MB",
+	"only keep objects modified since, e.g. 2024-01-01, RFC3339 or 7d":                         "只保留这个时间之后修改的对象，例如 2024-01-01、RFC3339 或 7d",
+	"only keep objects modified until, e.g. 2024-06-30, RFC3339 or 24h":                        "只保留这个时间之前修改的对象，例如 2024-06-30、RFC3339 或 24h",
+	"drop zero-byte `folder/` markers":                                                         "丢弃 0 字节的 `folder/` 目录占位对象",
+	"local directory to mirror into":                                                           "镜像到这个本地目录",
+	"number of concurrent downloads":                                                           "同时下载的个数",
+	"total bandwidth limit per second, e.g. 512K, 2MB":                                         "总带宽限制（每秒），例如 512K、2MB",
+	"previously exported .csv or .json file, instead of -u":                                    "之前导出的 .csv 或 .json 文件，代替 -u",
+	"do not show the progress line":                                                            "不显示进度",
+	"verify downloads against the ETag (MD5) and quarantine corrupted files":                   "用 ETag（MD5）校验下载的文件，损坏的文件隔离起来",
+	"write a CSV report of verification mismatches to this file":                               "校验不一致的文件写成 CSV 报告",
+	"part size of multipart uploads, e.g. 8MB; inferred when empty":                            "分片上传的分片大小，例如 8MB；不填时自动推断",
+	"directory for corrupted files (default <dir>/.quarantine)":                                "损坏文件的隔离目录（默认 <dir>/.quarantine）",
+	"output format: table, json or html":                                                       "输出格式：table、json 或 html",
+	"write the diff to this file instead of stdout":                                            "差异写到这个文件，不输出到 stdout",
+	"company keyword, can be repeated":                                                         "公司关键字，可以重复",
+	"endpoint template with {name}, can be repeated (default https://{name}.s3.amazonaws.com)": "带 {name} 的 endpoint 模板，可以重复（默认 https://{name}.s3.amazonaws.com）",
+	"wordlist file for prefixes/suffixes, one word per line (default built-in list)":           "前缀/后缀词表文件，每行一个（默认使用内置词表）",
+	"years appended to names, e.g. 2023,2024 (default the last 3 years)":                       "追加在名字后面的年份，例如 2023,2024（默认最近 3 年）",
+	"regions appended to names, e.g. cn-hangzhou,ap-east-1":                                    "追加在名字后面的地域，例如 cn-hangzhou,ap-east-1",
+	"number of concurrent probes":                                                              "同时探测的个数",
+	"write matched URLs to this file, one per line, usable with -l":                            "找到的 URL 写到这个文件，每行一个，可以直接交给 -l",
+	"also output buckets that exist but deny listing":                                          "也输出存在但拒绝列目录的 bucket",
+	"only print the candidate URLs":                                                            "只打印候选 URL，不探测",
+	"only print the listing URLs, one per line, usable with -l":                                "只打印列目录的 URL，每行一个，可以直接交给 -l",
+	"print the results as JSON":                                                                "以 JSON 输出",
+	"crawl every extracted bucket and print a batch summary":                                   "爬取提取出来的每个 bucket 并打印汇总",
+	"number of buckets crawled at the same time":                                               "同时爬取的 bucket 数",
+	"file keeping the last crawl (.json or .csv), so restarts do not lose history":             "保存上一次爬取结果的文件（.json 或 .csv），重启后不丢历史",
+	"POST each batch of events as a JSON array to this URL":                                    "每批事件以 JSON 数组 POST 到这个 URL",
+	"stop after this many crawls (0 = forever)":                                                "爬取这么多次后停止（0 表示一直运行）",
+	"time between crawls":                                                                      "两次爬取的间隔",
+
+	// web 页面
+	"Bucket browser":              "Bucket 浏览",
+	"Filter by name":              "按名字过滤",
+	"Name":                        "名称",
+	"Size":                        "大小",
+	"Last modified":               "修改时间",
+	"Type":                        "类型",
+	"Previous":                    "上一页",
+	"Next":                        "下一页",
+	"No objects":                  "没有对象",
+	"objects":                     "个对象",
+	"Preview":                     "预览",
+	"Object not in the listing":   "列表里没有这个对象",
+	"Object has no download link": "对象没有下载链接",
+	"Object is larger than the preview limit %v bytes":     "对象超过预览大小上限 %v 字节",
+	"Failed to fetch remote URL: %v":                       "请求远程 URL 失败: %v",
+	"Unexpected status: %v":                                "响应状态异常: %v",
+	"Content type %v can not be previewed":                 "不支持预览 %v 类型的内容",
+	"Encoding":                                             "编码",
+	"Showing the first %v rows":                            "只显示前 %v 行",
+	"Only csv, tsv and xlsx can be previewed as a table":   "只有 csv、tsv 和 xlsx 可以按表格预览",
+	"Failed to preview the table: %v":                      "表格预览失败: %v",
+	"Failed to generate the page: %v":                      "生成页面出错: %v",
+	"Failed to start the server: %v":                       "服务器启动失败: %v",
+	"Server started, open http://%v/ to browse the bucket": "服务器已启动，访问 http://%v/ 浏览 bucket",
+}
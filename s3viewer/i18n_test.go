@@ -0,0 +1,38 @@
+package s3viewer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"regexp"
+	"testing"
+)
+
+func TestT(t *testing.T) {
+	defer SetLang(LangEN)
+
+	assert.Equal(t, "Invalid -u: x", T("Invalid -u: %v", "x"))
+	assert.NoError(t, SetLang("ZH"))
+	assert.Equal(t, "-u 不正确: x", T("Invalid -u: %v", "x"))
+	// 没有翻译的原样返回
+	assert.Equal(t, "not in catalog 1", T("not in catalog %v", 1))
+	assert.Error(t, SetLang("fr"))
+	assert.Equal(t, LangZH, Lang())
+}
+
+func TestCatalogVerbs(t *testing.T) {
+	verbs := regexp.MustCompile(`%[-+# 0-9.]*[a-zA-Z%]`)
+	for en, zh := range zhMessages {
+		assert.Equal(t, verbs.FindAllString(en, -1), verbs.FindAllString(zh, -1), en)
+	}
+}
+
+func TestDetectLang(t *testing.T) {
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "")
+	t.Setenv("LANG", "zh_CN.UTF-8")
+	assert.Equal(t, LangZH, DetectLang())
+	t.Setenv("LC_ALL", "en_US.UTF-8")
+	assert.Equal(t, LangEN, DetectLang())
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LANG", "")
+	assert.Equal(t, LangEN, DetectLang())
+}
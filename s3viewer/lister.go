@@ -0,0 +1,132 @@
+package s3viewer
+
+import (
+	"context"
+	"io"
+)
+
+// Lister 逐页拉取列表，不把所有页攒在内存里。用法：
+//
+//	lister := NewLister(url, maxPage)
+//	for {
+//		file, err := lister.Next(ctx)
+//		if err == io.EOF {
+//			break
+//		}
+//		...
+//	}
+//
+// 没有更多结果（最后一页、到达 maxPage、翻页失败）时返回 io.EOF；ctx 取消时返回 ctx.Err()。
+// 具体怎么拉取、怎么翻页由 Backend 决定
+type Lister struct {
+	backend  Backend
+	url      string // 第一页的 location
+	location string
+	maxPage  int
+	err      error // 找不到来源时，第一次拉取返回这个错误
+
+	page       []File
+	pos        int
+	pages      int // 已拉取的页数
+	count      int // 已返回的条数
+	done       bool
+	incomplete bool // 有页只解析出一部分，或者翻页失败
+}
+
+// NewLister 按 location 选择来源（见 BackendFor），maxPage <= 0 时只拉取一页
+func NewLister(location string, maxPage int) *Lister {
+	backend, err := BackendFor(location)
+	l := NewBackendLister(backend, location, maxPage)
+	l.err = err
+	return l
+}
+
+// NewBackendLister 使用指定的来源
+func NewBackendLister(backend Backend, location string, maxPage int) *Lister {
+	if maxPage <= 0 {
+		maxPage = 1
+	}
+	return &Lister{backend: backend, url: location, location: location, maxPage: maxPage}
+}
+
+// Url 第一页的 location
+func (l *Lister) Url() string {
+	return l.url
+}
+
+// Backend 使用的来源
+func (l *Lister) Backend() Backend {
+	return l.backend
+}
+
+// Next 返回下一个对象，当前页用完时才请求下一页
+func (l *Lister) Next(ctx context.Context) (File, error) {
+	for l.pos >= len(l.page) {
+		page, err := l.NextPage(ctx)
+		if err != nil {
+			return File{}, err
+		}
+		l.page, l.pos = page, 0
+	}
+	file := l.page[l.pos]
+	l.pos++
+	return file, nil
+}
+
+// NextPage 请求下一页，返回这一页的对象（可能为空）；和 Next 不要混用
+func (l *Lister) NextPage(ctx context.Context) ([]File, error) {
+	if l.err != nil {
+		return nil, l.err
+	}
+	if l.done || l.pages >= l.maxPage {
+		return nil, io.EOF
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	result, err := l.backend.ListPage(ctx, l.location)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if result == nil {
+			return nil, err
+		}
+		// 解析出一部分时继续
+		Logger().Warn(T("partial page"), "url", l.location, "err", err)
+		l.incomplete = true
+	}
+	l.pages++
+	l.count += len(result.Files)
+	Logger().Info(T("page fetched"), "page", l.pages, "objects", len(result.Files))
+
+	// 判断是否有必要翻页
+	next, err := l.backend.NextPage(l.location, result)
+	switch {
+	case err != nil:
+		Logger().Warn(T("cannot fetch next page"), "page", l.pages, "err", err)
+		l.done = true
+		l.incomplete = true
+	case next == "":
+		Logger().Debug(T("last page"), "page", l.pages, "objects", len(result.Files))
+		l.done = true
+	default:
+		l.location = next
+	}
+	return result.Files, nil
+}
+
+// Pages 已拉取的页数
+func (l *Lister) Pages() int {
+	return l.pages
+}
+
+// Complete 是否完整地拉到了最后一页；到达 maxPage 还有下一页、翻页失败、有页只解析出一部分时为 false
+func (l *Lister) Complete() bool {
+	return l.done && !l.incomplete
+}
+
+// Count 已拉取的对象数
+func (l *Lister) Count() int {
+	return l.count
+}
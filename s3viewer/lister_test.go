@@ -0,0 +1,127 @@
+package s3viewer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// pagedServer 按 marker 分页返回 pages 里的 key，每页一组
+func pagedServer(pages [][]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := 0
+		if marker := r.URL.Query().Get("marker"); marker != "" {
+			for i, keys := range pages {
+				if len(keys) > 0 && keys[len(keys)-1] == marker {
+					page = i + 1
+				}
+			}
+		}
+		fmt.Fprintf(w, "<ListBucketResult><IsTruncated>%v</IsTruncated>", page < len(pages)-1)
+		for _, key := range pages[page] {
+			fmt.Fprintf(w, "<Contents><Key>%s</Key><Size>1</Size></Contents>", key)
+		}
+		fmt.Fprint(w, "</ListBucketResult>")
+	}))
+}
+
+func TestListerNext(t *testing.T) {
+	server := pagedServer([][]string{{"a.txt"}, {"b.txt"}, {"c.sql"}})
+	defer server.Close()
+
+	lister := NewLister(server.URL+"/", 10)
+	var keys []string
+	for {
+		file, err := lister.Next(context.Background())
+		if err == io.EOF {
+			break
+		}
+		if !assert.NoError(t, err) {
+			return
+		}
+		keys = append(keys, file.Key)
+	}
+	assert.Equal(t, []string{"a.txt", "b.txt", "c.sql"}, keys)
+	assert.Equal(t, 3, lister.Pages())
+	assert.Equal(t, 3, lister.Count())
+
+	// 到达 maxPage 后停止
+	lister = NewLister(server.URL+"/", 1)
+	files, err := lister.NextPage(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+	_, err = lister.NextPage(context.Background())
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestStreamResult(t *testing.T) {
+	server := pagedServer([][]string{{"a.txt", "b.sql"}, {"c.sql"}})
+	defer server.Close()
+
+	var buf bytes.Buffer
+	filter := &Filter{Exts: []string{"sql"}}
+	written, err := StreamResult(context.Background(), NewLister(server.URL+"/", 10), NewCSVSink(&buf), filter)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, written)
+
+	loaded, err := LoadCSV(&buf)
+	assert.NoError(t, err)
+	if assert.Len(t, loaded.Files, 2) {
+		assert.Equal(t, "b.sql", loaded.Files[0].Key)
+		assert.Equal(t, server.URL+"/c.sql", loaded.Files[1].Link)
+	}
+
+	// 取消后不再请求，已写出的部分（这里只有表头）仍然可读
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	buf.Reset()
+	written, err = StreamResult(ctx, NewLister(server.URL+"/", 10), NewCSVSink(&buf), nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 0, written)
+	loaded, err = LoadCSV(&buf)
+	assert.NoError(t, err)
+	assert.Empty(t, loaded.Files)
+}
+
+// failingSink Write 或 Flush 返回错误，记录 End 有没有被调用
+type failingSink struct {
+	writeErr, flushErr error
+	ended              bool
+}
+
+func (f *failingSink) Begin(url string) error { return nil }
+func (f *failingSink) Write(file File) error  { return f.writeErr }
+func (f *failingSink) Flush() error           { return f.flushErr }
+func (f *failingSink) End() error {
+	f.ended = true
+	return errors.New("end")
+}
+
+func TestStreamResultEndsSinkOnError(t *testing.T) {
+	server := pagedServer([][]string{{"a.txt"}, {"b.txt"}})
+	defer server.Close()
+
+	writeErr := errors.New("disk full")
+	sink := &failingSink{writeErr: writeErr}
+	_, err := StreamResult(context.Background(), NewLister(server.URL+"/", 10), sink, nil)
+	assert.ErrorIs(t, err, writeErr)
+	assert.True(t, sink.ended)
+
+	flushErr := errors.New("broken pipe")
+	sink = &failingSink{flushErr: flushErr}
+	written, err := StreamResult(context.Background(), NewLister(server.URL+"/", 10), sink, nil)
+	assert.ErrorIs(t, err, flushErr)
+	assert.Equal(t, 1, written)
+	assert.True(t, sink.ended)
+
+	// 没有别的错误时返回 End 的错误
+	sink = &failingSink{}
+	_, err = StreamResult(context.Background(), NewLister(server.URL+"/", 10), sink, nil)
+	assert.EqualError(t, err, "end")
+}
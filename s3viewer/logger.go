@@ -0,0 +1,22 @@
+package s3viewer
+
+import (
+	"log/slog"
+	"sync/atomic"
+)
+
+var logger atomic.Pointer[slog.Logger]
+
+// SetLogger 替换库里使用的日志；为 nil 时恢复为 slog.Default()。
+// 嵌入到其他程序时可以传入 slog.New(slog.NewTextHandler(io.Discard, nil)) 关闭日志
+func SetLogger(l *slog.Logger) {
+	logger.Store(l)
+}
+
+// Logger 库里使用的日志，默认为 slog.Default()（没有设置过时和 log 包一样写到 stderr）
+func Logger() *slog.Logger {
+	if l := logger.Load(); l != nil {
+		return l
+	}
+	return slog.Default()
+}
@@ -0,0 +1,34 @@
+package s3viewer
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSetLogger(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<ListBucketResult><IsTruncated>false</IsTruncated><Contents><Key>a.txt</Key></Contents></ListBucketResult>`)
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	SetLogger(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})))
+	defer SetLogger(nil)
+
+	_, err := LoadRemoteHTTPRecursive(server.URL+"/", 5)
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), `"msg":"listing done","objects":1,"pages":1`)
+	// Debug 级别的请求日志被过滤掉
+	assert.NotContains(t, buf.String(), "http get")
+
+	// 请求失败只返回错误，由调用方决定怎么记录
+	buf.Reset()
+	_, err = HttpGet("http://127.0.0.1:1/")
+	assert.Error(t, err)
+	assert.Empty(t, buf.String())
+}
@@ -2,19 +2,20 @@ package s3viewer
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
-	"encoding/csv"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
-	"log"
 	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"regexp"
-	"text/tabwriter"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -37,36 +38,81 @@ type ListBucketResult struct {
 }
 
 type File struct {
-	Key          string `xml:"Key"`
-	LastModified string `xml:"LastModified"`
-	Size         int    `xml:"Size"`
+	Key          string    `xml:"Key"`
+	LastModified time.Time `xml:"LastModified"`
+	ETag         string    `xml:"ETag"`
+	Size         int       `xml:"Size"`
 	Link         string
+
+	// 以下字段由 Sniffer 补充
+	ContentType        string            `json:",omitempty"`
+	ContentDisposition string            `json:",omitempty"`
+	Metadata           map[string]string `json:",omitempty"` // x-amz-meta-* 用户元数据
+	DetectedType       string            `json:",omitempty"` // 根据文件头识别出的类型
+	TypeMismatch       bool              `json:",omitempty"` // 扩展名和内容不符
+
+	// 以下字段由 Classifier 补充
+	Category string `json:",omitempty"` // 敏感文件分类，例如 credential、backup
+	Severity string `json:",omitempty"` // critical、high、medium、low、info
+
+	// 压缩包里的文件（Key 形如 archive.zip!/inner/path），记录所在压缩包的 Key，Link 指向压缩包
+	Archive string `json:",omitempty"`
 }
 
-func HttpGet(url string) (resp *http.Response, err error) {
-	// 创建一个自定义的HTTP客户端(30秒超时，忽略 TLS 证书问题)
+// UnmarshalXML 解析 <Contents>，LastModified 转成 time.Time，ETag 去掉两侧引号
+// 各家 S3 兼容实现的时间格式不统一，解析失败时保留零值，不让整页解析失败
+func (f *File) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var raw struct {
+		Key          string `xml:"Key"`
+		LastModified string `xml:"LastModified"`
+		ETag         string `xml:"ETag"`
+		Size         int    `xml:"Size"`
+	}
+	if err := d.DecodeElement(&raw, &start); err != nil {
+		return err
+	}
+	f.Key = raw.Key
+	f.Size = raw.Size
+	f.ETag = strings.Trim(strings.TrimSpace(raw.ETag), `"`)
+	if t, err := ParseTime(raw.LastModified); err == nil {
+		f.LastModified = t
+	} else if raw.LastModified != "" {
+		Logger().Debug(T("invalid LastModified"), "key", raw.Key, "err", err)
+	}
+	return nil
+}
+
+// NewHTTPClient 创建爬取和下载共用的 HTTP 客户端(30秒连接超时，忽略 TLS 证书问题)
+// timeout 是请求的总超时时间，为 0 时不限制（下载大文件时用）
+func NewHTTPClient(timeout time.Duration) *http.Client {
 	tr := &http.Transport{
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, // 忽略SSL证书验证
 		DialContext: (&net.Dialer{
 			Timeout:   30 * time.Second, // 设置建立连接的超时时间
 			KeepAlive: 30 * time.Second,
 		}).DialContext,
+		IdleConnTimeout: 90 * time.Second, // web 服务长期运行，空闲连接不能一直留着
 	}
 
-	client := &http.Client{
+	return &http.Client{
 		Transport: tr,
-		Timeout:   45 * time.Second, // 设置请求的总超时时间
+		Timeout:   timeout,
 	}
-	// 使用自定义的客户端发起GET请求
-	log.Printf("Http Get [%v]\n", url)
-	// 创建一个HTTP请求
-	req, err := http.NewRequest("GET", url, nil)
+}
+
+// NewRequest 创建一个带浏览器请求头的 HTTP 请求
+func NewRequest(method string, url string) (*http.Request, error) {
+	return NewRequestContext(context.Background(), method, url)
+}
+
+// NewRequestContext 同 NewRequest，ctx 取消时请求随之中断
+func NewRequestContext(ctx context.Context, method string, url string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
 	if err != nil {
-		fmt.Println("Error creating request:", err)
 		return nil, err
 	}
 
-	// 设置User-Agent和其他自定义请求头xw
+	// 设置User-Agent和其他自定义请求头
 	headers := map[string]string{
 		"Accept":             "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7",
 		"Accept-Encoding":    "identity",
@@ -81,13 +127,27 @@ func HttpGet(url string) (resp *http.Response, err error) {
 	for k, v := range headers {
 		req.Header.Set(k, v)
 	}
-	response, err := client.Do(req)
+	return req, nil
+}
+
+func HttpGet(url string) (resp *http.Response, err error) {
+	return HttpGetContext(context.Background(), url)
+}
+
+// HttpGetContext 同 HttpGet，ctx 取消时请求随之中断
+func HttpGetContext(ctx context.Context, url string) (resp *http.Response, err error) {
+	// 创建一个自定义的HTTP客户端(45秒总超时)
+	client := NewHTTPClient(45 * time.Second)
+	// 使用自定义的客户端发起GET请求
+	Logger().Debug(T("http get"), "url", url)
+	// 创建一个HTTP请求
+	req, err := NewRequestContext(ctx, "GET", url)
 	if err != nil {
-		fmt.Println("Error fetching URL:", err)
 		return nil, err
-	} else {
-		return response, nil
 	}
+
+	// 错误交给调用方处理，这里不打印
+	return client.Do(req)
 }
 
 func tryGetNextPageURL(currentUrl string, result ListBucketResult) (string, error) {
@@ -109,14 +169,16 @@ func tryGetNextPageURL(currentUrl string, result ListBucketResult) (string, erro
 	*/
 	var nextUrl = currentUrl
 	var err error = nil
-	// 创建一个新的 URL 查询参数结构体
 	var u *url.URL
-	query := url.Values{}
 
 	u, err = url.Parse(currentUrl)
 	if err != nil {
 		return currentUrl, fmt.Errorf("invalid URL: %w", err)
 	}
+	// 保留 prefix、delimiter 等参数，只替换翻页用的参数
+	query := u.Query()
+	query.Del("marker")
+	query.Del("continuation-token")
 
 	//fmt.Printf("result-> %+v \n", result)
 	// try v2
@@ -129,7 +191,7 @@ func tryGetNextPageURL(currentUrl string, result ListBucketResult) (string, erro
 		u.RawQuery = query.Encode()
 		nextUrl = u.String()
 
-		log.Printf("尝试请求下一页: %v", nextUrl)
+		Logger().Debug(T("next page"), "url", nextUrl)
 		return nextUrl, err
 	}
 
@@ -146,7 +208,7 @@ func tryGetNextPageURL(currentUrl string, result ListBucketResult) (string, erro
 			query.Set("marker", lastItemMarker)
 			nextUrl = u.String()
 		} else {
-			err = fmt.Errorf("[!]无法翻页，应该是不支持翻页")
+			err = errors.New(T("cannot page, the target does not seem to support paging"))
 		}
 	}
 
@@ -155,80 +217,55 @@ func tryGetNextPageURL(currentUrl string, result ListBucketResult) (string, erro
 	nextUrl = u.String()
 
 	if currentUrl == nextUrl {
-		err = fmt.Errorf("[!]两次 URL完全相同，可能是目标部署了「忽略参数的CDN」，这种情况无法翻页")
+		err = errors.New(T("the next page URL is the same as the current one, a CDN ignoring parameters?"))
 	}
 
-	log.Printf("尝试请求下一页: %v", nextUrl)
+	Logger().Debug(T("next page"), "url", nextUrl)
 	return nextUrl, err
 }
 
 func LoadRemoteHTTPRecursive(url string, maxPage int) (*ListBucketResult, error) {
+	return LoadRemoteHTTPRecursiveContext(context.Background(), url, maxPage)
+}
+
+// LoadRemoteHTTPRecursiveContext 同 LoadRemoteHTTPRecursive；ctx 取消或超时时停止翻页，
+// 返回已经拉取到的结果和 ctx.Err()，调用方可以保存这部分结果
+func LoadRemoteHTTPRecursiveContext(ctx context.Context, url string, maxPage int) (*ListBucketResult, error) {
 	// e.g.: http://s3.example.com/
 	// 如果不支持翻页，就打印warning，退化到LoadRemoteHTTP
-	if maxPage <= 0 {
-		maxPage = 1
-	}
-	var acutalPage int
-
-	var allResults ListBucketResult
-
-	for page := 0; page < maxPage; page++ {
-		acutalPage = page + 1
-		response, err := HttpGet(url)
-		if err != nil {
-			return nil, fmt.Errorf("Failed to fetch remote URL: %w", err)
-		}
-		defer response.Body.Close()
-
-		if response.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("Failed to fetch remote URL: %s", response.Status)
-		}
-
-		body, err := io.ReadAll(response.Body)
-		if err != nil {
-			return nil, fmt.Errorf("Failed to read response body: %w", err)
-		}
-
-		xmlContent, err := findS3XMLString(string(body))
-		if err != nil {
-			return nil, fmt.Errorf("Failed to find S3 XML string: %w", err)
-		}
-
-		xmlContent = sanitizeXMLContent(xmlContent)
-
-		result, err := parseXMLToListBucketResult(xmlContent)
-		log.Printf("第 %v 页结果条数: %v", acutalPage, len(result.Files))
-		if err != nil {
-			log.Printf("Failed to unmarshal XML: %v", err)
-		}
+	return loadAll(ctx, NewBackendLister(s3Backend{}, url, maxPage))
+}
 
-		result, err = result.MergeUrlAndFillLinks(url)
-		if err != nil {
-			log.Printf("Failed to fill link into results: %v", err)
-		}
+// LoadContext 按 location 选择来源（S3 URL、本地文件...，见 BackendFor），拉取最多 maxPage 页；
+// 中断时和 LoadRemoteHTTPRecursiveContext 一样返回已经拉取到的结果和 ctx.Err()
+func LoadContext(ctx context.Context, location string, maxPage int) (*ListBucketResult, error) {
+	return loadAll(ctx, NewLister(location, maxPage))
+}
 
-		allResults.Files = append(allResults.Files, result.Files...)
+func loadAll(ctx context.Context, lister *Lister) (*ListBucketResult, error) {
+	allResults := ListBucketResult{Url: lister.Url()}
 
-		// 判断是否有必要翻页
-		if !result.IsTruncated {
-			log.Printf("不必翻页，本页已经返回了全部结果（%v）", len(result.Files))
+	for {
+		files, err := lister.NextPage(ctx)
+		if err == io.EOF {
 			break
 		}
-
-		url, err = tryGetNextPageURL(url, *result)
 		if err != nil {
-			log.Printf("翻页失败，错误: %v", err)
-			break
+			if ctx.Err() != nil {
+				Logger().Warn(T("listing interrupted"), "objects", len(allResults.Files), "pages", lister.Pages())
+				return &allResults, ctx.Err()
+			}
+			return nil, err
 		}
+		allResults.Files = append(allResults.Files, files...)
 	}
-	log.Printf("[+]结果总条数: [%v], 已拉取页数: [%v]", len(allResults.Files), acutalPage)
+	Logger().Info(T("listing done"), "objects", len(allResults.Files), "pages", lister.Pages())
 	return &allResults, nil
 }
 
-func LoadRemoteHTTP(url string) (*ListBucketResult, error) {
-	// 获取远程 URL 的内容
-	// e.g.: http://s3.example.com
-	response, err := HttpGet(url)
+// fetchListing 请求一页列表，返回响应体
+func fetchListing(ctx context.Context, url string) ([]byte, error) {
+	response, err := HttpGetContext(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to fetch remote URL: %w", err)
 	}
@@ -243,83 +280,77 @@ func LoadRemoteHTTP(url string) (*ListBucketResult, error) {
 	if err != nil {
 		return nil, fmt.Errorf("Failed to read response body: %w", err)
 	}
+	return body, nil
+}
 
-	// 提取 <ListBucketResult> 标签及其内容
-	// 假设 findS3XMLString 和 sanitizeXMLContent 函数已经更新为处理字符串输入
-	xmlContent, err := findS3XMLString(string(body))
-	if err != nil {
-		return nil, fmt.Errorf("Failed to find S3 XML string: %w", err)
-	}
-
-	xmlContent = sanitizeXMLContent(xmlContent)
-
-	// 解析 XML 内容为对象
-	result, err := parseXMLToListBucketResult(xmlContent)
-	if err != nil {
-		return nil, fmt.Errorf("Failed to unmarshal XML: %w", err)
-	}
+func LoadRemoteHTTP(url string) (*ListBucketResult, error) {
+	return LoadRemoteHTTPContext(context.Background(), url)
+}
 
-	result, err = result.MergeUrlAndFillLinks(url)
+// LoadRemoteHTTPContext 同 LoadRemoteHTTP，ctx 取消时请求随之中断
+func LoadRemoteHTTPContext(ctx context.Context, url string) (*ListBucketResult, error) {
+	// 获取远程 URL 的内容
+	// e.g.: http://s3.example.com
+	result, err := s3Backend{}.ListPage(ctx, url)
 	if err != nil {
-		log.Printf("Failed to fill link into results: %v", err)
+		return nil, err
 	}
-	return result, err
+	return result, nil
 }
 
+// LoadFile 读取保存在本地的列表页面（XML，或者嵌着 XML 的 HTML），出错时返回错误
 func LoadFile(path string) (*ListBucketResult, error) {
-	// 读取 XML 文件内容
-	//fileText, err := ioutil.ReadFile("/Users/dpdu/Desktop/opt/s3view_dev/s3viewer-go/test/h2-html.xml")
-	fileText, err := ioutil.ReadFile(path)
-	if err != nil {
-		log.Fatalf("Failed to read XML file: %v", err)
-	}
-
-	// 提取 <ListBucketResult> 标签及其内容
-	xmlContent, err := findS3XMLString(string(fileText))
-	if err != nil {
-		log.Fatalf("Failed to find S3 XML string: %v", err)
-	}
-
-	// 预处理 XML 内容
-	xmlContent = sanitizeXMLContent(xmlContent)
+	return fileBackend{}.ListPage(context.Background(), path)
+}
 
-	// 解析 XML 内容为对象
-	result, err := parseXMLToListBucketResult(xmlContent)
-	if err != nil {
-		log.Fatalf("Failed to unmarshal XML: %v", err)
+// LoadFiles 读取多个保存下来的页面并合并，参数可以是 glob，例如 pages/*.xml；
+// 同一个 Key 只保留第一次出现的，顺序按文件名排序后的顺序
+func LoadFiles(patterns ...string) (*ListBucketResult, error) {
+	var paths []string
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %v: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no file matches %v", pattern)
+		}
+		sort.Strings(matches)
+		paths = append(paths, matches...)
 	}
 
-	// 打印解析后的对象
-	for _, file := range result.Files {
-		fmt.Printf("%v\n", file)
+	results := make([]*ListBucketResult, 0, len(paths))
+	for _, path := range paths {
+		result, err := LoadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", path, err)
+		}
+		results = append(results, result)
 	}
-	return result, nil
+	return MergeResults(results...), nil
 }
 
-func PrintResult(result *ListBucketResult) error {
-	// 如果 filePath 不为空，则将输出写入文件
-	var output *os.File
-
-	// 输出到标准输出
-	output = os.Stdout
-
-	// 创建一个新的 tabwriter
-	writer := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
-
-	// 打印表头
-	fmt.Fprintln(writer, "Key\tSize\tLastModifiedDate")
-
-	// 遍历文件并打印每一行的内容
-	for _, file := range result.Files {
-		// Todo: fileSize 可以统一换算成合适的单位
-		// Todo: Date 可以排个倒序，最新的在最前面
-		fmt.Fprintf(writer, "%s\t%s\t%s\n", file.Key, fmt.Sprint(file.Size), file.LastModified)
+// MergeResults 合并多页结果，按 Key 去重，保留第一次出现的；Url 取第一页的
+func MergeResults(results ...*ListBucketResult) *ListBucketResult {
+	merged := new(ListBucketResult)
+	seen := make(map[string]bool)
+	for _, result := range results {
+		if merged.Url == "" {
+			merged.Url = result.Url
+		}
+		for _, file := range result.Files {
+			if !seen[file.Key] {
+				seen[file.Key] = true
+				merged.Files = append(merged.Files, file)
+			}
+		}
 	}
+	return merged
+}
 
-	// 刷新和清理 tabwriter
-	writer.Flush()
-
-	return nil
+// PrintResult 以表格形式把结果写到 w，列、单位等由 opts 控制
+func PrintResult(w io.Writer, result *ListBucketResult, opts PrintOptions) error {
+	return WriteResult(NewTableSink(w, opts), result)
 }
 
 // 将 ListBucketResult 对象转换为 CSV 格式，并保存到指定的文件中
@@ -329,35 +360,9 @@ func SaveResultToCSVFile(result *ListBucketResult, filePath string) error {
 	if err != nil {
 		return fmt.Errorf("Failed to create output file: %w", err)
 	}
-	defer func(file *os.File) {
-		err := file.Close()
-		if err != nil {
-
-		}
-	}(file)
-
-	// 创建 CSV 写入器
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
-
-	// 写入 CSV 头部
-	headers := []string{"Key", "Size", "LastModified", "Link"}
-	if err := writer.Write(headers); err != nil {
-		return fmt.Errorf("Failed to write CSV headers: %w", err)
-	}
+	defer file.Close()
 
-	// 写入文件条目
-	for _, entry := range result.Files {
-		if err != nil {
-			return fmt.Errorf("Failed to join URL: %w", err)
-		}
-		record := []string{entry.Key, fmt.Sprintf("%d", entry.Size), entry.LastModified, entry.Link}
-		if err := writer.Write(record); err != nil {
-			return fmt.Errorf("Failed to write CSV record: %w", err)
-		}
-	}
-
-	return nil
+	return WriteResult(NewCSVSink(file), result)
 }
 
 // 查找并提取 <ListBucketResult> 标签及其内容
@@ -393,16 +398,15 @@ func parseXMLToListBucketResult(xmlContent []byte) (*ListBucketResult, error) {
 	return &result, nil
 }
 
+// MergeUrlAndFillLinks 记录列目录的 URL，并根据它拼出每个对象的下载链接（去掉查询参数，key 逐段转义）
 func (result *ListBucketResult) MergeUrlAndFillLinks(u string) (*ListBucketResult, error) {
 	result.Url = u
-	var err error = nil
+	base, err := ParseBucketURL(u, "")
+	if err != nil {
+		return result, fmt.Errorf("Failed to join URL: %w", err)
+	}
 	for i := range result.Files {
-		currentFileLink, err := url.JoinPath(result.Url, result.Files[i].Key)
-		if err != nil {
-			log.Printf("Failed to join URL: %v, %v", result.Url, result.Files[i].Key)
-			err = fmt.Errorf("Failed to join URL: %w", err)
-		}
-		result.Files[i].Link = currentFileLink
+		result.Files[i].Link = base.ObjectURL(result.Files[i].Key)
 	}
-	return result, err
+	return result, nil
 }
@@ -1,11 +1,14 @@
 package s3viewer
 
 import (
+	"context"
 	"encoding/xml"
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"io"
 	"log"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
@@ -219,7 +222,7 @@ func TestPrintResult(t *testing.T) {
 	if result, err := LoadFile("../test/h2-html.xml"); err != nil {
 		log.Fatalf("Failed to load file: %v", err)
 	} else {
-		if err := PrintResult(result); err != nil {
+		if err := PrintResult(os.Stdout, result, PrintOptions{}); err != nil {
 			t.Fatalf("Failed to print result: %v", err)
 		}
 	}
@@ -325,3 +328,56 @@ func TestLoadRemoteHTTPRecursive_CanDeal_CDNIgnoreParameters(t *testing.T) {
 	// 断言文件内容不为空
 	assert.NotEmpty(t, content, "CSV content is empty")
 }
+
+func TestLoadRemoteHTTPRecursiveContext_Cancel(t *testing.T) {
+	// 每页一个文件、永远有下一页；拉到第 3 页时取消，应该返回前面已经拉到的结果
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	page := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page++
+		if page == 3 {
+			cancel()
+		}
+		fmt.Fprintf(w, `<ListBucketResult><IsTruncated>true</IsTruncated><Contents><Key>%03d.txt</Key><Size>1</Size></Contents></ListBucketResult>`, page)
+	}))
+	defer server.Close()
+
+	result, err := LoadRemoteHTTPRecursiveContext(ctx, server.URL+"/", 100)
+	assert.ErrorIs(t, err, context.Canceled)
+	if assert.NotNil(t, result) {
+		assert.GreaterOrEqual(t, len(result.Files), 2)
+		assert.Less(t, len(result.Files), 100)
+		assert.Equal(t, server.URL+"/001.txt", result.Files[0].Link)
+	}
+}
+
+func TestLoadFile_Errors(t *testing.T) {
+	_, err := LoadFile("../test/missing.xml")
+	assert.Error(t, err)
+
+	path := filepath.Join(t.TempDir(), "page.html")
+	assert.NoError(t, os.WriteFile(path, []byte("<html>Access Denied</html>"), 0644))
+	_, err = LoadFile(path)
+	assert.Error(t, err)
+}
+
+func TestLoadFiles(t *testing.T) {
+	h1, err := LoadFile("../test/h1.xml")
+	assert.NoError(t, err)
+	h2, err := LoadFile("../test/h2-html.xml")
+	assert.NoError(t, err)
+
+	// 同一页保存了两次，去重
+	result, err := LoadFiles("../test/h1.xml", "../test/h1.xml")
+	assert.NoError(t, err)
+	assert.Len(t, result.Files, len(h1.Files))
+
+	result, err = LoadFiles("../test/*.xml")
+	assert.NoError(t, err)
+	assert.Equal(t, MergeResults(h1, h2).Files, result.Files)
+	assert.Equal(t, "../test/h1.xml", result.Url)
+
+	_, err = LoadFiles("../test/*.json")
+	assert.Error(t, err)
+}
@@ -0,0 +1,204 @@
+package s3viewer
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// DefaultScanMaxSize 默认只扫描 10MiB 以内的对象
+const DefaultScanMaxSize = 10 << 20
+
+// Scanner 下载候选对象，提取文本后检测密钥和个人信息
+type Scanner struct {
+	Client    *http.Client // 为 nil 时使用 NewHTTPClient(2min)
+	Workers   int          // 并发数，<=0 时为 4
+	MaxSize   int64        // 单个对象的大小上限，<=0 时为 DefaultScanMaxSize
+	Detectors []Detector   // 为空时使用 DefaultDetectors
+}
+
+// ScanResult 单个对象的扫描结果
+type ScanResult struct {
+	Key      string
+	Link     string
+	Size     int
+	Findings []Finding
+	Err      string `json:",omitempty"`
+}
+
+// Total 所有类型的命中次数之和
+func (r ScanResult) Total() int {
+	total := 0
+	for _, f := range r.Findings {
+		total += f.Count
+	}
+	return total
+}
+
+// Candidates 挑出可以提取文本并且没有超过大小上限的对象
+func (s *Scanner) Candidates(files []File) []File {
+	maxSize := s.maxSize()
+	var candidates []File
+	for _, file := range files {
+		if file.Link == "" || file.Archive != "" || strings.HasSuffix(file.Key, "/") || int64(file.Size) > maxSize {
+			continue
+		}
+		if CanExtractText(file.Key) {
+			candidates = append(candidates, file)
+		}
+	}
+	return candidates
+}
+
+func (s *Scanner) maxSize() int64 {
+	if s.MaxSize > 0 {
+		return s.MaxSize
+	}
+	return DefaultScanMaxSize
+}
+
+// Scan 扫描 Candidates(files)，返回每个候选对象的结果
+func (s *Scanner) Scan(files []File) []ScanResult {
+	return s.ScanContext(context.Background(), files)
+}
+
+// ScanContext 同 Scan；ctx 取消后不再开始新的扫描，没来得及扫描的对象 Err 为 ctx.Err()
+func (s *Scanner) ScanContext(ctx context.Context, files []File) []ScanResult {
+	candidates := s.Candidates(files)
+	workers := s.Workers
+	if workers <= 0 {
+		workers = 4
+	}
+	client := s.Client
+	if client == nil {
+		client = NewHTTPClient(2 * time.Minute)
+	}
+
+	results := make([]ScanResult, len(candidates))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = s.scanOne(ctx, client, candidates[i])
+				if results[i].Err != "" {
+					Logger().Warn(T("scan failed"), "key", candidates[i].Key, "err", results[i].Err)
+				}
+			}
+		}()
+	}
+	fed := feedJobs(ctx, jobs, len(candidates))
+	wg.Wait()
+	for i := fed; i < len(candidates); i++ {
+		results[i] = ScanResult{Key: candidates[i].Key, Link: candidates[i].Link, Size: candidates[i].Size, Err: ctx.Err().Error()}
+	}
+	return results
+}
+
+func (s *Scanner) scanOne(ctx context.Context, client *http.Client, file File) ScanResult {
+	result := ScanResult{Key: file.Key, Link: file.Link, Size: file.Size}
+
+	data, err := fetchLimited(ctx, client, file.Link, s.maxSize())
+	if err != nil {
+		result.Err = err.Error()
+		return result
+	}
+	text, err := ExtractText(file.Key, data)
+	if err != nil {
+		result.Err = err.Error()
+		return result
+	}
+
+	detectors := s.Detectors
+	if len(detectors) == 0 {
+		detectors = DefaultDetectors
+	}
+	result.Findings = Detect(text, detectors)
+	return result
+}
+
+// fetchLimited 下载对象内容，超过 maxSize 时返回错误，不会把超大的响应读进内存
+func fetchLimited(ctx context.Context, client *http.Client, link string, maxSize int64) ([]byte, error) {
+	req, err := NewRequestContext(ctx, "GET", link)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET: %v", resp.Status)
+	}
+	if resp.ContentLength > maxSize {
+		return nil, fmt.Errorf("object too large: %v > %v", resp.ContentLength, maxSize)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("object too large: > %v", maxSize)
+	}
+	return data, nil
+}
+
+// PrintScanReport 以表格形式打印有命中的对象，每类一行
+func PrintScanReport(w io.Writer, results []ScanResult) error {
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "Key\tType\tCount\tSamples")
+	for _, r := range results {
+		for _, f := range r.Findings {
+			fmt.Fprintf(writer, "%s\t%s\t%d\t%s\n", r.Key, f.Type, f.Count, strings.Join(f.Samples, ", "))
+		}
+	}
+	return writer.Flush()
+}
+
+// SaveScanReport 保存扫描报告，.json 保存完整结果，其余保存为 CSV（每个对象的每类命中一行）
+func SaveScanReport(results []ScanResult, filePath string) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("Failed to create report file: %w", err)
+	}
+	defer file.Close()
+
+	if strings.EqualFold(filepath.Ext(filePath), ".json") {
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(results)
+	}
+
+	writer := csv.NewWriter(file)
+	if err := writer.Write([]string{"Key", "Link", "Type", "Count", "Samples", "Error"}); err != nil {
+		return fmt.Errorf("Failed to write CSV headers: %w", err)
+	}
+	for _, r := range results {
+		if len(r.Findings) == 0 && r.Err != "" {
+			if err := writer.Write([]string{r.Key, r.Link, "", "", "", r.Err}); err != nil {
+				return fmt.Errorf("Failed to write CSV record: %w", err)
+			}
+		}
+		for _, f := range r.Findings {
+			record := []string{r.Key, r.Link, f.Type, strconv.Itoa(f.Count), strings.Join(f.Samples, " | "), ""}
+			if err := writer.Write(record); err != nil {
+				return fmt.Errorf("Failed to write CSV record: %w", err)
+			}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
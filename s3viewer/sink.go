@@ -0,0 +1,310 @@
+package s3viewer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Sink 一种输出格式。调用顺序：Begin 一次，Write 每个对象一次，End 一次
+type Sink interface {
+	Begin(url string) error
+	Write(file File) error
+	End() error
+}
+
+// SinkFactory 创建写到 w 的 Sink，opts 只有表格用到
+type SinkFactory func(w io.Writer, opts PrintOptions) Sink
+
+type sinkEntry struct {
+	name    string
+	exts    []string
+	factory SinkFactory
+}
+
+var sinks []sinkEntry
+
+// RegisterSink 注册一种输出格式，exts 是对应的扩展名（带点），按文件名选择格式时使用
+func RegisterSink(name string, exts []string, factory SinkFactory) {
+	sinks = append(sinks, sinkEntry{name: name, exts: exts, factory: factory})
+}
+
+// SinkNames 已注册的格式，按名字排序
+func SinkNames() []string {
+	names := make([]string, len(sinks))
+	for i, entry := range sinks {
+		names[i] = entry.name
+	}
+	sort.Strings(names)
+	return names
+}
+
+func lookupSink(name string) (SinkFactory, bool) {
+	for _, entry := range sinks {
+		if strings.EqualFold(entry.name, name) {
+			return entry.factory, true
+		}
+	}
+	return nil, false
+}
+
+func lookupSinkByExt(path string) (SinkFactory, bool) {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, entry := range sinks {
+		for _, e := range entry.exts {
+			if e == ext {
+				return entry.factory, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func init() {
+	RegisterSink("table", []string{".txt"}, func(w io.Writer, opts PrintOptions) Sink { return NewTableSink(w, opts) })
+	RegisterSink("csv", []string{".csv"}, func(w io.Writer, opts PrintOptions) Sink { return NewCSVSink(w) })
+	RegisterSink("json", []string{".json"}, func(w io.Writer, opts PrintOptions) Sink { return NewJSONSink(w) })
+	RegisterSink("jsonl", []string{".jsonl", ".ndjson"}, func(w io.Writer, opts PrintOptions) Sink { return NewJSONLSink(w) })
+}
+
+// OpenSink 按 spec 打开一个输出：
+//
+//	csv、json...     对应格式，写到 stdout
+//	json:out.txt     指定格式，写到文件
+//	out.csv          按扩展名选择格式，不认识的扩展名保存为 CSV（和 SaveResult 一致）
+//	-                表格，写到 stdout
+//
+// 写到文件的 Sink 在 End 时关闭文件
+func OpenSink(spec string, stdout io.Writer, opts PrintOptions) (Sink, error) {
+	if spec == "-" {
+		return NewTableSink(stdout, opts), nil
+	}
+	if factory, ok := lookupSink(spec); ok {
+		return factory(stdout, opts), nil
+	}
+
+	factory, path := SinkFactory(nil), spec
+	if name, rest, ok := strings.Cut(spec, ":"); ok {
+		if f, ok := lookupSink(name); ok {
+			factory, path = f, rest
+		}
+	}
+	if factory == nil {
+		var ok bool
+		if factory, ok = lookupSinkByExt(path); !ok {
+			factory, _ = lookupSink("csv")
+		}
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create output file: %w", err)
+	}
+	return &fileSink{Sink: factory(file, opts), file: file}, nil
+}
+
+// fileSink End 之后关闭文件
+type fileSink struct {
+	Sink
+	file *os.File
+}
+
+func (f *fileSink) Flush() error {
+	if flusher, ok := f.Sink.(interface{ Flush() error }); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
+
+func (f *fileSink) End() error {
+	err := f.Sink.End()
+	if closeErr := f.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// MultiSink 同时写到多个 Sink；出错时返回第一个错误，End 会调用每一个 Sink 的 End
+func MultiSink(sinks ...Sink) Sink {
+	return multiSink(sinks)
+}
+
+type multiSink []Sink
+
+func (m multiSink) Begin(url string) error {
+	for _, s := range m {
+		if err := s.Begin(url); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m multiSink) Write(file File) error {
+	for _, s := range m {
+		if err := s.Write(file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m multiSink) Flush() error {
+	for _, s := range m {
+		if flusher, ok := s.(interface{ Flush() error }); ok {
+			if err := flusher.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (m multiSink) End() error {
+	var first error
+	for _, s := range m {
+		if err := s.End(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// WriteResult 把整个结果写给 sink
+func WriteResult(sink Sink, result *ListBucketResult) error {
+	if err := sink.Begin(result.Url); err != nil {
+		sink.End()
+		return err
+	}
+	for _, file := range result.Files {
+		if err := sink.Write(file); err != nil {
+			sink.End()
+			return err
+		}
+	}
+	return sink.End()
+}
+
+// TableSink 和 PrintResult 一样的表格，End 时对齐输出
+type TableSink struct {
+	w    *tabwriter.Writer
+	opts PrintOptions
+	row  []string
+}
+
+func NewTableSink(w io.Writer, opts PrintOptions) *TableSink {
+	if len(opts.Columns) == 0 {
+		opts.Columns = DefaultColumns
+	}
+	return &TableSink{w: tabwriter.NewWriter(w, 0, 0, 2, ' ', 0), opts: opts}
+}
+
+func (t *TableSink) Begin(url string) error {
+	titles := make([]string, len(t.opts.Columns))
+	for i, c := range t.opts.Columns {
+		title, ok := columnTitles[c]
+		if !ok {
+			return fmt.Errorf("unknown column: %v", c)
+		}
+		titles[i] = title
+	}
+	t.row = make([]string, len(t.opts.Columns))
+	_, err := fmt.Fprintln(t.w, strings.Join(titles, "\t"))
+	return err
+}
+
+func (t *TableSink) Write(file File) error {
+	for i, c := range t.opts.Columns {
+		t.row[i] = formatColumn(file, c, t.opts)
+	}
+	_, err := fmt.Fprintln(t.w, strings.Join(t.row, "\t"))
+	return err
+}
+
+func (t *TableSink) End() error {
+	return t.w.Flush()
+}
+
+// CSVSink 和 SaveResultToCSVFile 的格式相同
+type CSVSink struct {
+	w *csv.Writer
+}
+
+func NewCSVSink(w io.Writer) *CSVSink {
+	return &CSVSink{w: csv.NewWriter(w)}
+}
+
+func (c *CSVSink) Begin(url string) error {
+	if err := c.w.Write(csvHeaders); err != nil {
+		return fmt.Errorf("Failed to write CSV headers: %w", err)
+	}
+	return nil
+}
+
+func (c *CSVSink) Write(file File) error {
+	if err := c.w.Write(csvRecord(file)); err != nil {
+		return fmt.Errorf("Failed to write CSV record: %w", err)
+	}
+	return nil
+}
+
+func (c *CSVSink) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *CSVSink) End() error {
+	return c.Flush()
+}
+
+// JSONSink 和 SaveResultToJSONFile 一样输出整个 ListBucketResult，需要攒下所有对象，End 时才写出
+type JSONSink struct {
+	w      io.Writer
+	result ListBucketResult
+}
+
+func NewJSONSink(w io.Writer) *JSONSink {
+	return &JSONSink{w: w}
+}
+
+func (j *JSONSink) Begin(url string) error {
+	j.result = ListBucketResult{Url: url}
+	return nil
+}
+
+func (j *JSONSink) Write(file File) error {
+	j.result.Files = append(j.result.Files, file)
+	return nil
+}
+
+func (j *JSONSink) End() error {
+	encoder := json.NewEncoder(j.w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(&j.result)
+}
+
+// JSONLSink 每行一个对象的 JSON，适合边爬边写
+type JSONLSink struct {
+	encoder *json.Encoder
+}
+
+func NewJSONLSink(w io.Writer) *JSONLSink {
+	return &JSONLSink{encoder: json.NewEncoder(w)}
+}
+
+func (j *JSONLSink) Begin(url string) error {
+	return nil
+}
+
+func (j *JSONLSink) Write(file File) error {
+	return j.encoder.Encode(file)
+}
+
+func (j *JSONLSink) End() error {
+	return nil
+}
@@ -0,0 +1,56 @@
+package s3viewer
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMultiSink(t *testing.T) {
+	result := &ListBucketResult{Url: "http://s3.example.com/", Files: []File{
+		{Key: "a.txt", Size: 1, Link: "http://s3.example.com/a.txt"},
+		{Key: "db/backup.sql", Size: 2048, ETag: "abc"},
+	}}
+	dir := t.TempDir()
+	var stdout bytes.Buffer
+	specs := []string{"-", filepath.Join(dir, "out.csv"), filepath.Join(dir, "out.json"), filepath.Join(dir, "out.jsonl"), "json:" + filepath.Join(dir, "out.txt")}
+
+	var opened []Sink
+	for _, spec := range specs {
+		sink, err := OpenSink(spec, &stdout, PrintOptions{Columns: []string{ColumnKey, ColumnSize}, Human: true})
+		if !assert.NoError(t, err, spec) {
+			return
+		}
+		opened = append(opened, sink)
+	}
+	assert.NoError(t, WriteResult(MultiSink(opened...), result))
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.Contains(t, lines[2], "2.0 KiB")
+
+	for _, name := range []string{"out.csv", "out.json", "out.jsonl"} {
+		loaded, err := LoadSnapshot(filepath.Join(dir, name))
+		if assert.NoError(t, err, name) && assert.Len(t, loaded.Files, 2, name) {
+			assert.Equal(t, "db/backup.sql", loaded.Files[1].Key, name)
+			assert.Equal(t, "abc", loaded.Files[1].ETag, name)
+		}
+	}
+	// json: 前缀指定格式，不看扩展名
+	file, err := os.Open(filepath.Join(dir, "out.txt"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer file.Close()
+	loaded, err := LoadJSON(file)
+	if assert.NoError(t, err) {
+		assert.Equal(t, result.Url, loaded.Url)
+	}
+
+	assert.Contains(t, SinkNames(), "jsonl")
+	_, err = OpenSink(filepath.Join(dir, "missing", "out.csv"), &stdout, PrintOptions{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,216 @@
+package s3viewer
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// SaveResultToJSONFile 将 ListBucketResult 保存为 JSON，可以用 LoadSnapshot 再读回来
+func SaveResultToJSONFile(result *ListBucketResult, filePath string) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("Failed to create output file: %w", err)
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(result); err != nil {
+		return fmt.Errorf("Failed to write JSON: %w", err)
+	}
+	return nil
+}
+
+// SaveResult 按扩展名选择格式保存结果：.json 保存为 JSON，.jsonl 保存为 JSONL，其余保存为 CSV
+func SaveResult(result *ListBucketResult, filePath string) error {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".json":
+		return SaveResultToJSONFile(result, filePath)
+	case ".jsonl":
+		return SaveResultToJSONLFile(result, filePath)
+	}
+	return SaveResultToCSVFile(result, filePath)
+}
+
+// LoadSnapshot 读取之前导出的 CSV/JSON/JSONL 文件，按扩展名判断格式
+func LoadSnapshot(filePath string) (*ListBucketResult, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open snapshot: %w", err)
+	}
+	defer file.Close()
+
+	var result *ListBucketResult
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".json":
+		result, err = LoadJSON(file)
+	case ".jsonl":
+		result, err = LoadJSONL(file)
+	case ".csv":
+		result, err = LoadCSV(file)
+	default:
+		return nil, fmt.Errorf("unsupported snapshot format: %v", filePath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load snapshot %v: %w", filePath, err)
+	}
+	return result, nil
+}
+
+// LoadJSON 读取 SaveResultToJSONFile 写出的 JSON
+func LoadJSON(r io.Reader) (*ListBucketResult, error) {
+	var result ListBucketResult
+	if err := json.NewDecoder(r).Decode(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// LoadCSV 读取 SaveResultToCSVFile 写出的 CSV，按表头识别列，旧版本导出的缺少 ETag 列也能读
+func LoadCSV(r io.Reader) (*ListBucketResult, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read CSV header: %w", err)
+	}
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[strings.TrimSpace(name)] = i
+	}
+	if _, ok := index["Key"]; !ok {
+		return nil, fmt.Errorf("CSV header has no Key column: %v", header)
+	}
+	column := func(record []string, name string) string {
+		if i, ok := index[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	var result ListBucketResult
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read CSV record: %w", err)
+		}
+
+		file := File{
+			Key:          column(record, "Key"),
+			Link:         column(record, "Link"),
+			ETag:         column(record, "ETag"),
+			ContentType:  column(record, "ContentType"),
+			DetectedType: column(record, "DetectedType"),
+			TypeMismatch: column(record, "TypeMismatch") == "true",
+			Category:     column(record, "Category"),
+			Severity:     column(record, "Severity"),
+			Archive:      column(record, "Archive"),
+		}
+		if s := column(record, "Size"); s != "" {
+			if file.Size, err = strconv.Atoi(s); err != nil {
+				return nil, fmt.Errorf("invalid Size of %v: %w", file.Key, err)
+			}
+		}
+		if file.LastModified, err = ParseTime(column(record, "LastModified")); err != nil {
+			return nil, fmt.Errorf("invalid LastModified of %v: %w", file.Key, err)
+		}
+		result.Files = append(result.Files, file)
+	}
+	return &result, nil
+}
+
+// SaveResultToJSONLFile 每行一个对象的 JSON，可以用 LoadSnapshot 再读回来
+func SaveResultToJSONLFile(result *ListBucketResult, filePath string) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("Failed to create output file: %w", err)
+	}
+	defer file.Close()
+
+	if err := WriteResult(NewJSONLSink(file), result); err != nil {
+		return fmt.Errorf("Failed to write JSON: %w", err)
+	}
+	return nil
+}
+
+// LoadJSONL 读取 SaveResultToJSONLFile 或 JSONLSink 写出的 JSONL
+func LoadJSONL(r io.Reader) (*ListBucketResult, error) {
+	var result ListBucketResult
+	decoder := json.NewDecoder(r)
+	for {
+		var file File
+		err := decoder.Decode(&file)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		result.Files = append(result.Files, file)
+	}
+	return &result, nil
+}
+
+// csvHeaders SaveResultToCSVFile 和 CSVSink 的表头
+var csvHeaders = []string{"Key", "Size", "LastModified", "Link", "ETag", "ContentType", "DetectedType", "TypeMismatch", "Category", "Severity", "Archive"}
+
+func csvRecord(entry File) []string {
+	return []string{entry.Key, fmt.Sprintf("%d", entry.Size), FormatTime(entry.LastModified), entry.Link, entry.ETag,
+		entry.ContentType, entry.DetectedType, formatBool(entry.TypeMismatch), entry.Category, entry.Severity, entry.Archive}
+}
+
+// StreamResult 边爬边写：每拉到一页就把（过滤后的）对象写给 sink，sink 实现了 Flush 时每页 Flush 一次，
+// 内存里只保留一页。filter 为 nil 时不过滤；返回写出的条数，ctx 取消时返回 ctx.Err()，已经写出的部分保留。
+// 不管成功与否都会调用 sink.End，返回的是最先出现的错误
+func StreamResult(ctx context.Context, lister *Lister, sink Sink, filter *Filter) (written int, err error) {
+	defer func() {
+		if endErr := sink.End(); err == nil {
+			err = endErr
+		}
+	}()
+	if filter != nil {
+		if err := filter.Compile(); err != nil {
+			return 0, err
+		}
+	}
+	if err := sink.Begin(lister.Url()); err != nil {
+		return 0, err
+	}
+	flusher, _ := sink.(interface{ Flush() error })
+	for {
+		files, err := lister.NextPage(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return written, err
+		}
+		for _, file := range files {
+			if filter != nil && !filter.Matches(file) {
+				continue
+			}
+			if err := sink.Write(file); err != nil {
+				return written, fmt.Errorf("Failed to write %v: %w", file.Key, err)
+			}
+			written++
+		}
+		if flusher != nil {
+			if err := flusher.Flush(); err != nil {
+				return written, err
+			}
+		}
+	}
+	Logger().Info(T("listing done"), "objects", lister.Count(), "written", written, "pages", lister.Pages())
+	return written, nil
+}
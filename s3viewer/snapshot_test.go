@@ -0,0 +1,51 @@
+package s3viewer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	result, err := parseXMLToListBucketResult(sanitizeXMLContent([]byte(specialXML)))
+	if err != nil {
+		t.Fatalf("Failed to parse XML to ListBucketResult: %v", err)
+	}
+	result, _ = result.MergeUrlAndFillLinks("http://s3.example.com/")
+
+	for _, name := range []string{"snapshot.csv", "snapshot.json", "snapshot.jsonl"} {
+		path := filepath.Join(t.TempDir(), name)
+		if err := SaveResult(result, path); err != nil {
+			t.Fatalf("Failed to save %v: %v", name, err)
+		}
+
+		loaded, err := LoadSnapshot(path)
+		if err != nil {
+			t.Fatalf("Failed to load %v: %v", name, err)
+		}
+		assert.Len(t, loaded.Files, len(result.Files), name)
+		for i := range result.Files {
+			assert.Equal(t, result.Files[i].Key, loaded.Files[i].Key, name)
+			assert.Equal(t, result.Files[i].Size, loaded.Files[i].Size, name)
+			assert.Equal(t, result.Files[i].ETag, loaded.Files[i].ETag, name)
+			assert.Equal(t, result.Files[i].Link, loaded.Files[i].Link, name)
+			assert.True(t, result.Files[i].LastModified.Equal(loaded.Files[i].LastModified), name)
+		}
+	}
+}
+
+func TestLoadCSVWithoutETag(t *testing.T) {
+	// 旧版本导出的 CSV 没有 ETag 列
+	csv := "Key,Size,LastModified,Link\na.txt,12,2023-05-22T08:49:07.000Z,http://s3.example.com/a.txt\n"
+	result, err := LoadCSV(strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("Failed to load CSV: %v", err)
+	}
+	assert.Len(t, result.Files, 1)
+	assert.Equal(t, 12, result.Files[0].Size)
+	assert.Equal(t, "", result.Files[0].ETag)
+
+	_, err = LoadSnapshot("snapshot.txt")
+	assert.Error(t, err)
+}
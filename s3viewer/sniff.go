@@ -0,0 +1,270 @@
+package s3viewer
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sniffLen 判断文件类型时读取的字节数，和 http.DetectContentType 一致
+const sniffLen = 512
+
+// 魔数表，http.DetectContentType 不认识的格式（压缩包、office、数据库、可执行文件等）在这里补充
+var magicNumbers = []struct {
+	offset int
+	magic  []byte
+	mime   string
+}{
+	{0, []byte("%PDF-"), "application/pdf"},
+	{0, []byte("PK\x03\x04"), "application/zip"},
+	{0, []byte("PK\x05\x06"), "application/zip"},
+	{0, []byte("\x1f\x8b"), "application/gzip"},
+	{0, []byte("7z\xbc\xaf\x27\x1c"), "application/x-7z-compressed"},
+	{0, []byte("Rar!\x1a\x07"), "application/vnd.rar"},
+	{0, []byte("BZh"), "application/x-bzip2"},
+	{0, []byte("\xfd7zXZ\x00"), "application/x-xz"},
+	{257, []byte("ustar"), "application/x-tar"},
+	{0, []byte("\xd0\xcf\x11\xe0\xa1\xb1\x1a\xe1"), "application/x-ole-storage"}, // doc/xls/ppt/msi
+	{0, []byte("SQLite format 3\x00"), "application/vnd.sqlite3"},
+	{0, []byte("\x7fELF"), "application/x-elf"},
+	{0, []byte("dex\n"), "application/vnd.android.dex"},
+	{0, []byte("-----BEGIN "), "application/x-pem-file"},
+}
+
+// 扩展名 -> 内容应该是的类型，用来发现扩展名和内容不符的文件（比如伪装成 .jpg 的压缩包）
+var extContentTypes = map[string][]string{
+	".pdf":     {"application/pdf"},
+	".zip":     {"application/zip"},
+	".docx":    {"application/zip"},
+	".xlsx":    {"application/zip"},
+	".pptx":    {"application/zip"},
+	".apk":     {"application/zip"},
+	".ipa":     {"application/zip"},
+	".jar":     {"application/zip"},
+	".war":     {"application/zip"},
+	".gz":      {"application/gzip", "application/x-gzip"},
+	".tgz":     {"application/gzip", "application/x-gzip"},
+	".7z":      {"application/x-7z-compressed"},
+	".rar":     {"application/vnd.rar", "application/x-rar-compressed"},
+	".bz2":     {"application/x-bzip2"},
+	".xz":      {"application/x-xz"},
+	".tar":     {"application/x-tar"},
+	".doc":     {"application/x-ole-storage"},
+	".xls":     {"application/x-ole-storage"},
+	".ppt":     {"application/x-ole-storage"},
+	".msi":     {"application/x-ole-storage"},
+	".db":      {"application/vnd.sqlite3"},
+	".sqlite":  {"application/vnd.sqlite3"},
+	".sqlite3": {"application/vnd.sqlite3"},
+	".exe":     {"application/vnd.microsoft.portable-executable"},
+	".dll":     {"application/vnd.microsoft.portable-executable"},
+	".png":     {"image/png"},
+	".jpg":     {"image/jpeg"},
+	".jpeg":    {"image/jpeg"},
+	".gif":     {"image/gif"},
+	".webp":    {"image/webp"},
+	".bmp":     {"image/bmp"},
+	".ico":     {"image/x-icon", "image/vnd.microsoft.icon"},
+	".mp4":     {"video/mp4"},
+	".mp3":     {"audio/mpeg"},
+	".txt":     {"text/"},
+	".csv":     {"text/"},
+	".log":     {"text/"},
+	".sql":     {"text/"},
+	".json":    {"text/", "application/json"},
+	".xml":     {"text/"},
+	".html":    {"text/"},
+	".htm":     {"text/"},
+	".js":      {"text/", "application/javascript"},
+	".css":     {"text/"},
+	".md":      {"text/"},
+	".yml":     {"text/"},
+	".yaml":    {"text/"},
+	".env":     {"text/"},
+	".pem":     {"application/x-pem-file", "text/"},
+	".key":     {"application/x-pem-file", "text/"},
+}
+
+// Sniffer 用 HEAD（以及可选的 Range GET）补充对象的类型信息
+type Sniffer struct {
+	Client  *http.Client // 为 nil 时使用 NewHTTPClient(30s)
+	Workers int          // 并发数，<=0 时为 8
+	Range   bool         // 额外用 `Range: bytes=0-511` 读文件头判断真实类型
+}
+
+// Enrich 并发补充 files 的 ContentType、Metadata、DetectedType 等字段，直接修改 files，返回失败的个数
+func (s *Sniffer) Enrich(files []File) int {
+	return s.EnrichContext(context.Background(), files)
+}
+
+// EnrichContext 同 Enrich，ctx 取消后不再发起新的请求
+func (s *Sniffer) EnrichContext(ctx context.Context, files []File) int {
+	workers := s.Workers
+	if workers <= 0 {
+		workers = 8
+	}
+	client := s.Client
+	if client == nil {
+		client = NewHTTPClient(30 * time.Second)
+	}
+
+	var failed int
+	var mu sync.Mutex
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if err := s.enrichOne(ctx, client, &files[i]); err != nil {
+					Logger().Warn(T("sniff failed"), "key", files[i].Key, "err", err)
+					mu.Lock()
+					failed++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+feed:
+	for i := range files {
+		// 目录占位对象和压缩包里的文件没什么好看的
+		if strings.HasSuffix(files[i].Key, "/") || files[i].Link == "" || files[i].Archive != "" {
+			continue
+		}
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return failed
+}
+
+func (s *Sniffer) enrichOne(ctx context.Context, client *http.Client, file *File) error {
+	req, err := NewRequestContext(ctx, "HEAD", file.Link)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HEAD: %v", resp.Status)
+	}
+	applyObjectHeaders(file, resp.Header)
+
+	// 空对象没有内容可看，Range 请求还会得到 416
+	if !s.Range || file.Size == 0 {
+		return nil
+	}
+	head, err := fetchHead(ctx, client, file.Link, sniffLen)
+	if err != nil {
+		return err
+	}
+	file.DetectedType = DetectContentType(head)
+	file.TypeMismatch = IsTypeMismatch(file.Key, file.DetectedType)
+	return nil
+}
+
+// applyObjectHeaders 从 HEAD 的响应头里取 Content-Type、Content-Length、Content-Disposition 和 x-amz-meta-*
+func applyObjectHeaders(file *File, header http.Header) {
+	file.ContentType = header.Get("Content-Type")
+	file.ContentDisposition = header.Get("Content-Disposition")
+	if file.Size == 0 {
+		if n, err := strconv.Atoi(header.Get("Content-Length")); err == nil {
+			file.Size = n
+		}
+	}
+	for name, values := range header {
+		lower := strings.ToLower(name)
+		for _, prefix := range []string{"x-amz-meta-", "x-oss-meta-", "x-cos-meta-", "x-goog-meta-"} {
+			if strings.HasPrefix(lower, prefix) && len(values) > 0 {
+				if file.Metadata == nil {
+					file.Metadata = make(map[string]string)
+				}
+				file.Metadata[strings.TrimPrefix(lower, prefix)] = values[0]
+			}
+		}
+	}
+}
+
+// fetchHead 用 Range GET 读取对象的前 n 个字节；服务端不支持 Range 时只读前 n 个字节就断开，空对象返回 nil
+func fetchHead(ctx context.Context, client *http.Client, link string, n int) ([]byte, error) {
+	req, err := NewRequestContext(ctx, "GET", link)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=0-%d", n-1))
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		// 空对象
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		return nil, fmt.Errorf("GET: %v", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, int64(n)))
+}
+
+// isPE 判断是不是 PE 可执行文件。只看 MZ 两个字节的话 MZ 开头的文本也会被当成 exe，
+// 所以还要按 0x3c 处的 e_lfanew 找到 PE\0\0 签名；签名不在 head 里时不认为是 PE
+func isPE(head []byte) bool {
+	if len(head) < 0x40 || !bytes.HasPrefix(head, []byte("MZ")) {
+		return false
+	}
+	offset := int64(binary.LittleEndian.Uint32(head[0x3c:]))
+	return offset >= 0x40 && offset+4 <= int64(len(head)) && bytes.Equal(head[offset:offset+4], []byte("PE\x00\x00"))
+}
+
+// DetectContentType 先查魔数表，查不到再交给 http.DetectContentType，返回不带参数的 MIME 类型
+func DetectContentType(head []byte) string {
+	if isPE(head) {
+		return "application/vnd.microsoft.portable-executable"
+	}
+	for _, m := range magicNumbers {
+		if len(head) >= m.offset+len(m.magic) && bytes.Equal(head[m.offset:m.offset+len(m.magic)], m.magic) {
+			return m.mime
+		}
+	}
+	mediaType, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil {
+		return "application/octet-stream"
+	}
+	return mediaType
+}
+
+// IsTypeMismatch 扩展名和实际内容是否矛盾；扩展名不认识或者内容类型识别不出来时返回 false
+func IsTypeMismatch(key string, detected string) bool {
+	if detected == "" || detected == "application/octet-stream" {
+		return false
+	}
+	ext := strings.ToLower(path.Ext(key))
+	expected, ok := extContentTypes[ext]
+	if !ok {
+		return false
+	}
+	for _, e := range expected {
+		if detected == e || strings.HasSuffix(e, "/") && strings.HasPrefix(detected, e) {
+			return false
+		}
+	}
+	return true
+}
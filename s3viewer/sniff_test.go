@@ -0,0 +1,109 @@
+package s3viewer
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDetectContentType(t *testing.T) {
+	tar := make([]byte, 512)
+	copy(tar[257:], "ustar")
+	pe := make([]byte, 512)
+	copy(pe, "MZ")
+	pe[0x3c] = 0x80
+	copy(pe[0x80:], "PE\x00\x00")
+
+	cases := map[string][]byte{
+		"application/pdf":           []byte("%PDF-1.7\n"),
+		"application/zip":           []byte("PK\x03\x04\x14\x00"),
+		"application/gzip":          []byte("\x1f\x8b\x08\x00"),
+		"application/x-tar":         tar,
+		"application/x-ole-storage": []byte("\xd0\xcf\x11\xe0\xa1\xb1\x1a\xe1\x00"),
+		"application/vnd.sqlite3":   []byte("SQLite format 3\x00"),
+		"image/png":                 []byte("\x89PNG\r\n\x1a\n"),
+		"text/plain":                []byte("hello world"),
+		"text/html":                 []byte("<html><body></body></html>"),
+		"application/vnd.microsoft.portable-executable": pe,
+	}
+	for want, head := range cases {
+		assert.Equal(t, want, DetectContentType(head), want)
+	}
+
+	// MZ 开头但没有 PE 头的不是可执行文件
+	assert.Equal(t, "text/plain", DetectContentType([]byte("MZ was here, nothing to see\n")))
+	assert.Equal(t, "application/octet-stream", DetectContentType(pe[:0x80]))
+}
+
+func TestIsTypeMismatch(t *testing.T) {
+	assert.True(t, IsTypeMismatch("photo.JPG", "application/zip"))
+	assert.True(t, IsTypeMismatch("notes.txt", "application/x-elf"))
+	assert.False(t, IsTypeMismatch("report.docx", "application/zip"))
+	assert.False(t, IsTypeMismatch("dump.sql", "text/plain"))
+	// 扩展名不认识、内容识别不出来时不报
+	assert.False(t, IsTypeMismatch("a.jlk", "application/zip"))
+	assert.False(t, IsTypeMismatch("a.jpg", "application/octet-stream"))
+}
+
+func TestSnifferEnrich(t *testing.T) {
+	objects := map[string][]byte{
+		"cat.jpg":    []byte("PK\x03\x04 this is really a zip"),
+		"report.pdf": []byte("%PDF-1.4 real pdf"),
+		"empty.txt":  {},
+	}
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/")
+		content, ok := objects[key]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method == "GET" {
+			ranges = append(ranges, r.Header.Get("Range"))
+		}
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+key+`"`)
+		w.Header().Set("X-Amz-Meta-Uploader", "alice")
+		http.ServeContent(w, r, key, time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	files := []File{
+		{Key: "cat.jpg", Link: server.URL + "/cat.jpg"},
+		{Key: "report.pdf", Link: server.URL + "/report.pdf"},
+		{Key: "dir/", Link: server.URL + "/dir/"},
+		{Key: "empty.txt", Link: server.URL + "/empty.txt"},
+	}
+
+	// 只发 HEAD
+	failed := (&Sniffer{Workers: 1}).Enrich(files)
+	assert.Equal(t, 0, failed)
+	assert.Equal(t, "image/jpeg", files[0].ContentType)
+	assert.Equal(t, `attachment; filename="cat.jpg"`, files[0].ContentDisposition)
+	assert.Equal(t, map[string]string{"uploader": "alice"}, files[0].Metadata)
+	assert.Equal(t, len(objects["cat.jpg"]), files[0].Size)
+	assert.Equal(t, "", files[0].DetectedType)
+	assert.Empty(t, ranges)
+
+	// HEAD + Range GET
+	failed = (&Sniffer{Workers: 1, Range: true}).Enrich(files)
+	assert.Equal(t, 0, failed)
+	assert.Equal(t, "application/zip", files[0].DetectedType)
+	assert.True(t, files[0].TypeMismatch)
+	assert.Equal(t, "application/pdf", files[1].DetectedType)
+	assert.False(t, files[1].TypeMismatch)
+	// 空对象不发 Range GET，不算失败
+	assert.Equal(t, []string{"bytes=0-511", "bytes=0-511"}, ranges)
+	assert.Equal(t, "", files[3].DetectedType)
+
+	// 服务端对空对象的 Range 返回 416 时当作空内容
+	head, err := fetchHead(context.Background(), http.DefaultClient, server.URL+"/empty.txt", sniffLen)
+	assert.NoError(t, err)
+	assert.Empty(t, head)
+}
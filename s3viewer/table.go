@@ -0,0 +1,567 @@
+package s3viewer
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Table 表格预览：前几行数据
+type Table struct {
+	Sheets    []string // xlsx 的工作表，csv/tsv 为空
+	Sheet     string   // 当前工作表
+	Encoding  string   // csv/tsv 识别出的编码：UTF-8 或 GB18030
+	Rows      [][]string
+	Truncated bool // 只读了前 MaxRows 行，或者只下载了文件开头
+}
+
+// IsTable 判断 Key 是否支持表格预览：csv、tsv、xlsx
+func IsTable(key string) bool {
+	switch strings.ToLower(path.Ext(key)) {
+	case ".csv", ".tsv", ".xlsx":
+		return true
+	}
+	return false
+}
+
+// TablePreviewer 用 Range 请求读取远程表格的前几行，不下载整个文件
+// csv/tsv 只下载开头；xlsx 只下载 zip 目录区、工作簿、sharedStrings 和要看的工作表
+type TablePreviewer struct {
+	Client   *http.Client // 为 nil 时使用 NewHTTPClient(2min)
+	MaxRows  int          // 最多读取的行数，<=0 时为 100
+	CSVBytes int64        // csv/tsv 下载开头的字节数，<=0 时为 256KiB
+	MaxBytes int64        // xlsx 最多下载的字节数，<=0 时为 16MiB
+}
+
+func (p *TablePreviewer) client() *http.Client {
+	if p.Client != nil {
+		return p.Client
+	}
+	return NewHTTPClient(2 * time.Minute)
+}
+
+func (p *TablePreviewer) maxRows() int {
+	if p.MaxRows > 0 {
+		return p.MaxRows
+	}
+	return 100
+}
+
+func (p *TablePreviewer) csvBytes() int64 {
+	if p.CSVBytes > 0 {
+		return p.CSVBytes
+	}
+	return 256 << 10
+}
+
+func (p *TablePreviewer) maxBytes() int64 {
+	if p.MaxBytes > 0 {
+		return p.MaxBytes
+	}
+	return 16 << 20
+}
+
+// Preview 读取 file 的前 MaxRows 行；sheet 为 xlsx 的工作表名，为空时读第一个
+func (p *TablePreviewer) Preview(ctx context.Context, file File, sheet string) (*Table, error) {
+	if file.Link == "" {
+		return nil, fmt.Errorf("no download link: %v", file.Key)
+	}
+	switch strings.ToLower(path.Ext(file.Key)) {
+	case ".csv":
+		return p.previewCSV(ctx, file, ',')
+	case ".tsv":
+		return p.previewCSV(ctx, file, '\t')
+	case ".xlsx":
+		return p.previewXLSX(ctx, file, sheet)
+	}
+	return nil, fmt.Errorf("unsupported table type: %v", file.Key)
+}
+
+func (p *TablePreviewer) previewCSV(ctx context.Context, file File, comma rune) (*Table, error) {
+	client := p.client()
+	limit := p.csvBytes()
+	data, total, err := fetchRange(ctx, client, file.Link, fmt.Sprintf("bytes=0-%d", limit-1))
+	if err != nil {
+		// 不支持 Range 时只读开头，读够了就断开
+		Logger().Debug(T("Range request failed, reading the head instead"), "url", file.Link, "err", err)
+		if data, err = fetchHead(ctx, client, file.Link, int(limit)); err != nil {
+			return nil, err
+		}
+		total = -1
+		if int64(len(data)) < limit {
+			total = int64(len(data))
+		}
+	}
+	return ReadCSVTable(data, comma, p.maxRows(), total != int64(len(data)))
+}
+
+func (p *TablePreviewer) previewXLSX(ctx context.Context, file File, sheet string) (*Table, error) {
+	client := p.client()
+	ra, err := newRangeReaderAt(ctx, client, file.Link, p.maxBytes())
+	if err != nil {
+		// 不支持 Range 时整个下载，但不超过 MaxBytes
+		Logger().Debug(T("Range request failed, downloading the whole file"), "url", file.Link, "err", err)
+		data, err := fetchHead(ctx, client, file.Link, int(p.maxBytes()+1))
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(data)) > p.maxBytes() {
+			return nil, fmt.Errorf("xlsx is larger than %v bytes and the server does not support Range", p.maxBytes())
+		}
+		return ReadXLSXTable(bytes.NewReader(data), int64(len(data)), sheet, p.maxRows())
+	}
+	return ReadXLSXTable(ra, ra.size, sheet, p.maxRows())
+}
+
+// ReadCSVTable 读取 csv/tsv 内容的前 maxRows 行，不是合法 UTF-8 时按 GB18030（兼容 GBK）解码
+// partial 表示 data 只是文件开头，最后一行可能不完整，会被丢掉
+func ReadCSVTable(data []byte, comma rune, maxRows int, partial bool) (*Table, error) {
+	table := &Table{Encoding: "UTF-8", Truncated: partial}
+	if partial {
+		if i := bytes.LastIndexByte(data, '\n'); i >= 0 {
+			data = data[:i+1]
+		}
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !utf8.Valid(data) {
+		decoded, err := simplifiedchinese.GB18030.NewDecoder().Bytes(data)
+		if err != nil {
+			return nil, fmt.Errorf("unknown encoding: %w", err)
+		}
+		data = decoded
+		table.Encoding = "GB18030"
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// 被截断的引号字段之类的错误，保留已经读到的行
+			if len(table.Rows) > 0 {
+				table.Truncated = true
+				break
+			}
+			return nil, err
+		}
+		if len(table.Rows) == maxRows {
+			table.Truncated = true
+			break
+		}
+		table.Rows = append(table.Rows, record)
+	}
+	return table, nil
+}
+
+// ReadXLSXTable 读取 xlsx 的工作表 sheet（为空时第一个）的前 maxRows 行
+// 只打开需要的几个部件，配合 rangeReaderAt 时只下载这些部分
+func ReadXLSXTable(r io.ReaderAt, size int64, sheet string, maxRows int) (*Table, error) {
+	reader, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("invalid xlsx: %w", err)
+	}
+	parts := make(map[string]*zip.File, len(reader.File))
+	for _, f := range reader.File {
+		parts[f.Name] = f
+	}
+
+	sheets, err := xlsxSheets(parts)
+	if err != nil {
+		return nil, err
+	}
+	if len(sheets) == 0 {
+		return nil, errors.New("xlsx has no sheets")
+	}
+	table := &Table{Sheet: sheets[0].name}
+	target := sheets[0].target
+	for _, s := range sheets {
+		table.Sheets = append(table.Sheets, s.name)
+		if s.name == sheet {
+			table.Sheet, target = s.name, s.target
+		}
+	}
+	if sheet != "" && sheet != table.Sheet {
+		return nil, fmt.Errorf("sheet not found: %v", sheet)
+	}
+
+	var shared []string
+	if f, ok := parts["xl/sharedStrings.xml"]; ok {
+		if shared, err = xlsxSharedStrings(f); err != nil {
+			return nil, err
+		}
+	}
+	f, ok := parts[target]
+	if !ok {
+		return nil, fmt.Errorf("sheet part not found: %v", target)
+	}
+	table.Rows, table.Truncated, err = xlsxRows(f, shared, maxRows)
+	if err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+// xlsx 解析的上限：压缩比很高的 zip 下载量很小，解压后却能撑爆内存
+const (
+	xlsxMaxPartBytes     = 64 << 20 // 每个部件最多解压的字节数
+	xlsxMaxSharedStrings = 1 << 20  // 最多读取的共享字符串数
+	xlsxMaxCellChars     = 32767    // 单元格最长的内容，和 Excel 的限制一样
+	xlsxMaxColumns       = 16384    // Excel 最大的列 XFD
+	tableMaxColumns      = 256      // 预览最多显示的列数
+)
+
+var errPartTooLarge = errors.New("xlsx part exceeds the decompressed size limit")
+
+// limitedPart 解压超过 n 字节时返回 errPartTooLarge，而不是像 io.LimitReader 那样返回 EOF
+type limitedPart struct {
+	io.ReadCloser
+	n int64
+}
+
+func (p *limitedPart) Read(b []byte) (int, error) {
+	if p.n <= 0 {
+		return 0, errPartTooLarge
+	}
+	if int64(len(b)) > p.n {
+		b = b[:p.n]
+	}
+	n, err := p.ReadCloser.Read(b)
+	p.n -= int64(n)
+	return n, err
+}
+
+// openPart 打开 zip 里的部件，解压量受 xlsxMaxPartBytes 限制
+func openPart(f *zip.File) (io.ReadCloser, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open %v: %w", f.Name, err)
+	}
+	return &limitedPart{ReadCloser: rc, n: xlsxMaxPartBytes}, nil
+}
+
+// writeCapped 往 sb 里写 b，总长度不超过 xlsxMaxCellChars 字节
+func writeCapped(sb *strings.Builder, b []byte) {
+	if room := xlsxMaxCellChars - sb.Len(); room < len(b) {
+		b = b[:max(room, 0)]
+	}
+	sb.Write(b)
+}
+
+type xlsxSheet struct {
+	name   string
+	target string // zip 里的路径，例如 xl/worksheets/sheet1.xml
+}
+
+// xlsxSheets 从 workbook.xml 和它的 rels 里按顺序读出工作表名和对应的部件
+func xlsxSheets(parts map[string]*zip.File) ([]xlsxSheet, error) {
+	var workbook struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			ID   string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodeZipXML(parts, "xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+	if err := decodeZipXML(parts, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+
+	targets := make(map[string]string)
+	for _, rel := range rels.Relationships {
+		if strings.HasPrefix(rel.Target, "/") {
+			targets[rel.ID] = strings.TrimPrefix(rel.Target, "/")
+		} else {
+			targets[rel.ID] = path.Join("xl", rel.Target)
+		}
+	}
+	var sheets []xlsxSheet
+	for _, s := range workbook.Sheets {
+		sheets = append(sheets, xlsxSheet{name: s.Name, target: targets[s.ID]})
+	}
+	return sheets, nil
+}
+
+func decodeZipXML(parts map[string]*zip.File, name string, v any) error {
+	f, ok := parts[name]
+	if !ok {
+		return fmt.Errorf("invalid xlsx: missing %v", name)
+	}
+	rc, err := openPart(f)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if err := xml.NewDecoder(rc).Decode(v); err != nil {
+		return fmt.Errorf("Failed to parse %v: %w", name, err)
+	}
+	return nil
+}
+
+// xlsxSharedStrings 读取共享字符串表，带格式的字符串由多个 <r><t> 拼成，注音 <rPh> 忽略
+// 超过 xlsxMaxSharedStrings 个或者解压量到了上限就停，后面的单元格显示为下标
+func xlsxSharedStrings(f *zip.File) ([]string, error) {
+	rc, err := openPart(f)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var (
+		strs    []string
+		sb      strings.Builder
+		inText  bool
+		inPhone bool
+	)
+	decoder := xml.NewDecoder(rc)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF || errors.Is(err, errPartTooLarge) {
+			return strs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse %v: %w", f.Name, err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "si":
+				sb.Reset()
+			case "t":
+				inText = true
+			case "rPh":
+				inPhone = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "si":
+				strs = append(strs, sb.String())
+				if len(strs) >= xlsxMaxSharedStrings {
+					return strs, nil
+				}
+			case "t":
+				inText = false
+			case "rPh":
+				inPhone = false
+			}
+		case xml.CharData:
+			if inText && !inPhone {
+				writeCapped(&sb, t)
+			}
+		}
+	}
+}
+
+// xlsxRows 流式读取工作表，读够 maxRows 行就停，不解压剩下的部分
+// 只保留前 tableMaxColumns 列；解压量到了上限时返回已经读到的行
+func xlsxRows(f *zip.File, shared []string, maxRows int) ([][]string, bool, error) {
+	rc, err := openPart(f)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rc.Close()
+
+	var (
+		rows     [][]string
+		row      []string
+		cellType string
+		cellCol  int
+		value    strings.Builder
+		inValue  bool
+		dropped  bool // 有列超出了 tableMaxColumns
+	)
+	decoder := xml.NewDecoder(rc)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return rows, dropped, nil
+		}
+		if errors.Is(err, errPartTooLarge) {
+			return rows, true, nil
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("Failed to parse %v: %w", f.Name, err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "row":
+				// 跳过的空行补上，保持行号
+				n := len(rows) + 1
+				if r, err := strconv.Atoi(xmlAttr(t, "r")); err == nil && r > n {
+					n = r
+				}
+				for len(rows) < n-1 && len(rows) < maxRows {
+					rows = append(rows, nil)
+				}
+				if len(rows) >= maxRows {
+					return rows, true, nil
+				}
+				row = []string{}
+			case "c":
+				cellType = xmlAttr(t, "t")
+				cellCol = len(row)
+				if ref := xmlAttr(t, "r"); ref != "" {
+					if col, ok := cellColumn(ref); ok {
+						cellCol = col
+					} else {
+						cellCol = xlsxMaxColumns // 非法的引用，丢掉这个单元格
+					}
+				}
+				value.Reset()
+			case "v", "t":
+				inValue = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "row":
+				rows = append(rows, row)
+			case "c":
+				if cellCol >= tableMaxColumns {
+					dropped = true
+					break
+				}
+				for len(row) < cellCol {
+					row = append(row, "")
+				}
+				row = append(row, cellValue(cellType, value.String(), shared))
+			case "v", "t":
+				inValue = false
+			}
+		case xml.CharData:
+			if inValue {
+				writeCapped(&value, t)
+			}
+		}
+	}
+}
+
+func xmlAttr(t xml.StartElement, name string) string {
+	for _, attr := range t.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// cellColumn 把 B12 之类的单元格引用换成从 0 开始的列号，超过 XFD 的不合法
+func cellColumn(ref string) (int, bool) {
+	col := 0
+	i := 0
+	for ; i < len(ref) && ref[i] >= 'A' && ref[i] <= 'Z'; i++ {
+		col = col*26 + int(ref[i]-'A'+1)
+		if col > xlsxMaxColumns {
+			return 0, false
+		}
+	}
+	if i == 0 {
+		return 0, false
+	}
+	return col - 1, true
+}
+
+func cellValue(typ, v string, shared []string) string {
+	switch typ {
+	case "s":
+		if i, err := strconv.Atoi(v); err == nil && i >= 0 && i < len(shared) {
+			return shared[i]
+		}
+	case "b":
+		if v == "1" {
+			return "TRUE"
+		}
+		return "FALSE"
+	}
+	return v
+}
+
+// rangeReaderAt 用 Range 请求实现 io.ReaderAt，按块缓存，下载总量超过 max 时报错
+type rangeReaderAt struct {
+	ctx     context.Context
+	client  *http.Client
+	link    string
+	size    int64
+	max     int64
+	fetched int64
+	blocks  map[int64][]byte
+}
+
+const rangeBlockSize = 64 << 10
+
+// newRangeReaderAt 先取最后一块，确认服务端支持 Range 并拿到文件大小；zip 的目录区就在末尾
+func newRangeReaderAt(ctx context.Context, client *http.Client, link string, max int64) (*rangeReaderAt, error) {
+	tail, size, err := fetchRange(ctx, client, link, fmt.Sprintf("bytes=-%d", rangeBlockSize))
+	if err != nil {
+		return nil, err
+	}
+	r := &rangeReaderAt{ctx: ctx, client: client, link: link, size: size, max: max, fetched: int64(len(tail)), blocks: make(map[int64][]byte)}
+	// 按块对齐缓存末尾，文件小于一块时 tail 就是整个文件
+	start := size - int64(len(tail))
+	for block := start / rangeBlockSize * rangeBlockSize; block < size; block += rangeBlockSize {
+		if block >= start {
+			end := min(block+rangeBlockSize, size)
+			r.blocks[block] = tail[block-start : end-start]
+		}
+	}
+	return r, nil
+}
+
+func (r *rangeReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n := 0
+	for n < len(p) {
+		pos := off + int64(n)
+		if pos >= r.size {
+			return n, io.EOF
+		}
+		block, err := r.block(pos / rangeBlockSize * rangeBlockSize)
+		if err != nil {
+			return n, err
+		}
+		n += copy(p[n:], block[pos%rangeBlockSize:])
+	}
+	return n, nil
+}
+
+func (r *rangeReaderAt) block(start int64) ([]byte, error) {
+	if data, ok := r.blocks[start]; ok {
+		return data, nil
+	}
+	end := min(start+rangeBlockSize, r.size)
+	if r.fetched+end-start > r.max {
+		return nil, fmt.Errorf("download limit of %v bytes exceeded", r.max)
+	}
+	data, _, err := fetchRange(r.ctx, r.client, r.link, fmt.Sprintf("bytes=%d-%d", start, end-1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) != end-start {
+		return nil, fmt.Errorf("short read: got %v bytes, want %v", len(data), end-start)
+	}
+	r.fetched += int64(len(data))
+	r.blocks[start] = data
+	return data, nil
+}
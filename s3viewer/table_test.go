@@ -0,0 +1,125 @@
+package s3viewer
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestReadCSVTable(t *testing.T) {
+	gbk, err := simplifiedchinese.GBK.NewEncoder().String("姓名,手机号\n张三,13800000000\n李四,13900000000\n")
+	if err != nil {
+		t.Fatalf("Failed to encode GBK: %v", err)
+	}
+	table, err := ReadCSVTable([]byte(gbk), ',', 10, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "GB18030", table.Encoding)
+	assert.Equal(t, [][]string{{"姓名", "手机号"}, {"张三", "13800000000"}, {"李四", "13900000000"}}, table.Rows)
+	assert.False(t, table.Truncated)
+
+	// BOM 去掉；只下载了开头时丢掉最后不完整的一行
+	table, err = ReadCSVTable([]byte("\xef\xbb\xbfa\tb\n1\t2\n3\t\"4"), '\t', 10, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "UTF-8", table.Encoding)
+	assert.Equal(t, [][]string{{"a", "b"}, {"1", "2"}}, table.Rows)
+	assert.True(t, table.Truncated)
+
+	table, err = ReadCSVTable([]byte("a\nb\nc\n"), ',', 2, false)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"a"}, {"b"}}, table.Rows)
+	assert.True(t, table.Truncated)
+}
+
+// buildXLSX 两个工作表的最小 xlsx，另外放一个压缩不了的大文件，用来确认预览时不会下载它
+func buildXLSX(t *testing.T) []byte {
+	t.Helper()
+	noise := make([]byte, 1<<20)
+	rand.New(rand.NewSource(1)).Read(noise)
+	return buildZip(t, map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="用户" sheetId="1" r:id="rId1"/><sheet name="Orders" sheetId="2" r:id="rId2"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="worksheet" Target="worksheets/sheet1.xml"/>
+<Relationship Id="rId2" Type="worksheet" Target="/xl/worksheets/sheet2.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst><si><t>姓名</t></si><si><r><t>手机</t></r><r><t>号</t></r><rPh><t>しゅ</t></rPh></si><si><t>张三</t></si></sst>`,
+		"xl/worksheets/sheet1.xml": `<worksheet><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c></row>
+<row r="2"><c r="A2" t="s"><v>2</v></c><c r="C2" t="inlineStr"><is><t>备注</t></is></c></row>
+<row r="4"><c r="B4"><v>13800000000</v></c><c r="C4" t="b"><v>1</v></c></row>
+<row r="5"><c r="A5"><v>5</v></c></row>
+</sheetData></worksheet>`,
+		"xl/worksheets/sheet2.xml": `<worksheet><sheetData><row r="1"><c r="A1" t="str"><v>order</v></c></row></sheetData></worksheet>`,
+		"xl/media/image1.png":      string(noise),
+	})
+}
+
+func TestReadXLSXTable(t *testing.T) {
+	data := buildXLSX(t)
+	table, err := ReadXLSXTable(strings.NewReader(string(data)), int64(len(data)), "", 4)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"用户", "Orders"}, table.Sheets)
+	assert.Equal(t, "用户", table.Sheet)
+	assert.Equal(t, [][]string{{"姓名", "手机号"}, {"张三", "", "备注"}, nil, {"", "13800000000", "TRUE"}}, table.Rows)
+	assert.True(t, table.Truncated)
+
+	table, err = ReadXLSXTable(strings.NewReader(string(data)), int64(len(data)), "Orders", 4)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"order"}}, table.Rows)
+	assert.False(t, table.Truncated)
+
+	_, err = ReadXLSXTable(strings.NewReader(string(data)), int64(len(data)), "nope", 4)
+	assert.Error(t, err)
+}
+
+func TestTablePreviewer(t *testing.T) {
+	xlsx := buildXLSX(t)
+	csv := "id,name\n" + strings.Repeat("1,abc\n", 100000)
+	server := newObjectServer(t, map[string][]byte{"users.xlsx": xlsx, "users.csv": []byte(csv)})
+	previewer := &TablePreviewer{MaxRows: 3}
+
+	table, err := previewer.Preview(context.Background(), File{Key: "users.xlsx", Link: server.URL + "/users.xlsx"}, "Orders")
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"order"}}, table.Rows)
+	// 只下载了目录区和需要的部件，没有下载图片
+	assert.Less(t, server.sent, 300*1024)
+	for _, r := range server.ranges {
+		assert.True(t, strings.HasPrefix(r, "bytes="), r)
+	}
+
+	server.sent = 0
+	table, err = previewer.Preview(context.Background(), File{Key: "users.csv", Link: server.URL + "/users.csv"}, "")
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"id", "name"}, {"1", "abc"}, {"1", "abc"}}, table.Rows)
+	assert.True(t, table.Truncated)
+	assert.LessOrEqual(t, server.sent, 256*1024)
+
+	_, err = previewer.Preview(context.Background(), File{Key: "users.pdf", Link: server.URL + "/users.pdf"}, "")
+	assert.Error(t, err)
+}
+
+func TestReadXLSXTableLimits(t *testing.T) {
+	files := map[string]string{
+		"xl/workbook.xml":            `<workbook xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="s" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships><Relationship Id="rId1" Target="worksheets/sheet1.xml"/></Relationships>`,
+		// 解压后超过上限的共享字符串表，下载量只有几十 KB
+		"xl/sharedStrings.xml": `<sst><si><t>a</t></si><si><t>` + strings.Repeat("x", xlsxMaxPartBytes) + `</t></si></sst>`,
+		"xl/worksheets/sheet1.xml": `<worksheet><sheetData><row r="1">` +
+			`<c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="ZZZZZZZ1"><v>1</v></c><c r="IW1"><v>2</v></c>` +
+			`<c r="C1" t="inlineStr"><is><t>` + strings.Repeat("y", 100000) + `</t></is></c></row></sheetData></worksheet>`,
+	}
+	data := buildZip(t, files)
+	assert.Less(t, len(data), 1<<20)
+
+	table, err := ReadXLSXTable(strings.NewReader(string(data)), int64(len(data)), "", 10)
+	assert.NoError(t, err)
+	assert.Len(t, table.Rows, 1)
+	row := table.Rows[0]
+	// 没读到的共享字符串显示为下标，超出预览宽度和不合法的列丢掉
+	assert.Equal(t, []string{"a", "1"}, row[:2])
+	assert.Len(t, row, 3)
+	assert.Len(t, row[2], xlsxMaxCellChars)
+	assert.True(t, table.Truncated)
+}
@@ -0,0 +1,241 @@
+package s3viewer
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// 当作纯文本处理的扩展名
+var plainTextExts = map[string]bool{
+	".txt": true, ".csv": true, ".tsv": true, ".log": true, ".sql": true, ".json": true, ".xml": true,
+	".yml": true, ".yaml": true, ".ini": true, ".conf": true, ".cfg": true, ".config": true, ".properties": true,
+	".env": true, ".md": true, ".html": true, ".htm": true, ".js": true, ".sh": true, ".bat": true, ".ps1": true,
+	".py": true, ".php": true, ".java": true, ".go": true, ".pem": true, ".key": true, ".toml": true,
+}
+
+// 没有扩展名、但通常是文本的文件名
+var plainTextNames = map[string]bool{
+	".env": true, "credentials": true, ".htpasswd": true, ".git-credentials": true, ".npmrc": true,
+	".pgpass": true, ".netrc": true, "id_rsa": true, "id_dsa": true, "id_ecdsa": true, "id_ed25519": true,
+}
+
+// maxExtractedText 从一个对象里最多解压的字节数；下载的大小有上限，但压缩比很高的 zip/PDF 解压后可能撑爆内存，
+// 到了上限就停，保留已经提取的文本
+var maxExtractedText int64 = 64 << 20
+
+// ooxml 里需要读取文本的部分
+var ooxmlParts = []*regexp.Regexp{
+	regexp.MustCompile(`^word/(document|header\d*|footer\d*|footnotes|endnotes|comments)\.xml$`),
+	regexp.MustCompile(`^xl/sharedStrings\.xml$`),
+	regexp.MustCompile(`^xl/worksheets/sheet\d+\.xml$`),
+	regexp.MustCompile(`^ppt/(slides/slide|notesSlides/notesSlide)\d+\.xml$`),
+	regexp.MustCompile(`^docProps/(core|app)\.xml$`),
+}
+
+// CanExtractText 判断是否支持从这个对象里提取文本
+func CanExtractText(key string) bool {
+	base := strings.ToLower(path.Base(key))
+	switch path.Ext(base) {
+	case ".docx", ".xlsx", ".pptx", ".pdf":
+		return true
+	}
+	return plainTextExts[path.Ext(base)] || plainTextNames[base]
+}
+
+// ExtractText 按扩展名从文件内容中提取文本：OOXML（docx/xlsx/pptx）、PDF、纯文本
+func ExtractText(key string, data []byte) (string, error) {
+	switch strings.ToLower(path.Ext(key)) {
+	case ".docx", ".xlsx", ".pptx":
+		return extractOOXMLText(data)
+	case ".pdf":
+		return extractPDFText(data)
+	}
+	if !CanExtractText(key) {
+		return "", fmt.Errorf("unsupported file type: %v", key)
+	}
+	return string(data), nil
+}
+
+// extractOOXMLText 解压 zip，读取正文、表格、幻灯片的 XML，只保留文本
+func extractOOXMLText(data []byte) (string, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("invalid OOXML: %w", err)
+	}
+
+	// 按文件名排序，保证 sheet1、sheet2 的顺序稳定
+	files := append([]*zip.File(nil), reader.File...)
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+
+	var sb strings.Builder
+	remaining := maxExtractedText
+	for _, f := range files {
+		if !matchAny(ooxmlParts, f.Name) {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return "", fmt.Errorf("Failed to open %v: %w", f.Name, err)
+		}
+		lr := &io.LimitedReader{R: rc, N: remaining}
+		err = xmlText(&sb, lr)
+		rc.Close()
+		remaining = lr.N
+		if remaining <= 0 {
+			// 到了上限，XML 被截断，解析错误不算
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("Failed to parse %v: %w", f.Name, err)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String(), nil
+}
+
+func matchAny(res []*regexp.Regexp, s string) bool {
+	for _, re := range res {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// xmlText 把 XML 的文本内容写进 sb；段落、行结束时换行，单元格结束时加制表符
+// 同一段落内的多个 run 直接拼接，避免把被拆开的号码切断
+func xmlText(sb *strings.Builder, r io.Reader) error {
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.CharData:
+			sb.Write(t)
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "p", "tr", "row", "si", "br":
+				sb.WriteString("\n")
+			case "c", "tc", "tab":
+				sb.WriteString("\t")
+			}
+		}
+	}
+}
+
+var (
+	// 流字典的结尾和 stream 关键字；字典从它前面最近的 obj 开始，不能从文件里第一个 << 开始匹配，
+	// 否则会把前面对象的字典（比如签名字典的 /Filter）也算进来
+	pdfStreamRe = regexp.MustCompile(`>>\s*stream\r?\n`)
+	// 文本对象里的 (literal) Tj、[(a) -3 (b)] TJ、' 和 " 操作符
+	pdfTextRe = regexp.MustCompile(`(?s)\[((?:\\.|[^\]])*)\]\s*TJ|\(((?:\\.|[^\\)])*)\)\s*(?:Tj|'|")`)
+	pdfArrRe  = regexp.MustCompile(`(?s)\(((?:\\.|[^\\)])*)\)`)
+)
+
+// extractPDFText 一个简化的 PDF 文本提取：解压 FlateDecode 的内容流，读取 Tj/TJ 里的字符串
+// 使用 CID 字体的中文 PDF 需要 ToUnicode 映射，这里提取不出来，只能覆盖 ASCII 内容（密钥、号码、邮箱等）
+func extractPDFText(data []byte) (string, error) {
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		return "", fmt.Errorf("invalid PDF header")
+	}
+
+	var sb strings.Builder
+	remaining := maxExtractedText
+	for pos := 0; remaining > 0; {
+		loc := pdfStreamRe.FindIndex(data[pos:])
+		if loc == nil {
+			break
+		}
+		dictEnd, start := pos+loc[0], pos+loc[1]
+		dictStart := pos
+		if i := bytes.LastIndex(data[pos:dictEnd], []byte("obj")); i >= 0 {
+			dictStart += i
+		}
+		dict := data[dictStart:dictEnd]
+		end := bytes.Index(data[start:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		stream := data[start : start+end]
+		// 下一个流从 endstream 之后找，不会匹配到流的内容里
+		pos = start + end + len("endstream")
+
+		if bytes.Contains(dict, []byte("/FlateDecode")) {
+			zr, err := zlib.NewReader(bytes.NewReader(stream))
+			if err != nil {
+				continue
+			}
+			decoded, err := io.ReadAll(io.LimitReader(zr, remaining))
+			zr.Close()
+			remaining -= int64(len(decoded))
+			if err != nil && len(decoded) == 0 {
+				continue
+			}
+			stream = decoded
+		} else if bytes.Contains(dict, []byte("/Filter")) {
+			// 其他编码（图片等）跳过
+			continue
+		}
+
+		for _, m := range pdfTextRe.FindAllSubmatch(stream, -1) {
+			if m[1] != nil {
+				for _, part := range pdfArrRe.FindAllSubmatch(m[1], -1) {
+					sb.WriteString(unescapePDFString(part[1]))
+				}
+			} else {
+				sb.WriteString(unescapePDFString(m[2]))
+			}
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String(), nil
+}
+
+// unescapePDFString 处理 PDF 字符串里的转义：\n \r \t \( \) \\ 和八进制 \ddd
+func unescapePDFString(b []byte) string {
+	var sb strings.Builder
+	for i := 0; i < len(b); i++ {
+		if b[i] != '\\' || i+1 >= len(b) {
+			sb.WriteByte(b[i])
+			continue
+		}
+		i++
+		switch c := b[i]; c {
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 't':
+			sb.WriteByte('\t')
+		case 'b', 'f':
+		case '\r', '\n':
+			// 续行
+		default:
+			if c >= '0' && c <= '7' {
+				v := 0
+				j := i
+				for ; j < len(b) && j < i+3 && b[j] >= '0' && b[j] <= '7'; j++ {
+					v = v*8 + int(b[j]-'0')
+				}
+				sb.WriteByte(byte(v))
+				i = j - 1
+			} else {
+				sb.WriteByte(c)
+			}
+		}
+	}
+	return sb.String()
+}
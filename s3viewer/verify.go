@@ -0,0 +1,190 @@
+package s3viewer
+
+import (
+	"crypto/md5"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// 校验结果
+const (
+	VerifyOK         = "ok"
+	VerifyMismatch   = "mismatch"
+	VerifyUnverified = "unverified" // 没有 ETag，或者分片大小推断不出来
+)
+
+// 常见工具默认的分片大小：aws cli 8MiB，SDK 5MiB，s3cmd 15MiB，以及其他常见配置
+var commonPartSizes = []int64{
+	5 << 20, 8 << 20, 15 << 20, 16 << 20, 25 << 20, 32 << 20,
+	50 << 20, 64 << 20, 100 << 20, 128 << 20, 256 << 20, 512 << 20,
+}
+
+// IsMultipartETag 分片上传的 ETag 形如 `<md5>-<分片数>`，不是内容的 MD5
+func IsMultipartETag(etag string) bool {
+	_, _, ok := splitMultipartETag(etag)
+	return ok
+}
+
+func splitMultipartETag(etag string) (string, int, bool) {
+	sum, count, found := strings.Cut(strings.Trim(etag, `"`), "-")
+	if !found || len(sum) != 32 {
+		return "", 0, false
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil || n <= 0 {
+		return "", 0, false
+	}
+	return sum, n, true
+}
+
+// MultipartETag 按 partSize 分片计算 S3 分片上传的 ETag：每片 MD5 拼接后再取 MD5，后缀分片数
+func MultipartETag(r io.Reader, partSize int64) (string, error) {
+	if partSize <= 0 {
+		return "", fmt.Errorf("invalid part size: %v", partSize)
+	}
+	var digests []byte
+	parts := 0
+	for {
+		h := md5.New()
+		n, err := io.CopyN(h, r, partSize)
+		if n > 0 {
+			digests = h.Sum(digests)
+			parts++
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	sum := md5.Sum(digests)
+	return fmt.Sprintf("%s-%d", hex.EncodeToString(sum[:]), parts), nil
+}
+
+// candidatePartSizes 返回分片数能对上的候选分片大小；partSize > 0 时只用它
+func candidatePartSizes(size int64, parts int, partSize int64) []int64 {
+	if partSize > 0 {
+		return []int64{partSize}
+	}
+	fits := func(p int64) bool {
+		return p > 0 && (size+p-1)/p == int64(parts)
+	}
+
+	var candidates []int64
+	seen := make(map[int64]bool)
+	add := func(p int64) {
+		if fits(p) && !seen[p] {
+			seen[p] = true
+			candidates = append(candidates, p)
+		}
+	}
+	for _, p := range commonPartSizes {
+		add(p)
+	}
+	// 按总大小 / 分片数反推，再向上取整到 MiB、MB
+	if parts > 1 {
+		p := (size + int64(parts) - 1) / int64(parts)
+		add((p + (1 << 20) - 1) / (1 << 20) * (1 << 20))
+		add((p + 999999) / 1000000 * 1000000)
+	}
+	return candidates
+}
+
+// VerifyFile 校验本地文件和 ETag 是否一致，返回状态和实际算出的 ETag
+// 分片上传的 ETag 需要分片大小，partSize 为 0 时按文件大小和分片数推断，推断的都对不上时返回 unverified，
+// 只有指定了 partSize 才会返回 mismatch
+func VerifyFile(path string, etag string, partSize int64) (string, string, error) {
+	etag = strings.ToLower(strings.Trim(etag, `"`))
+	if etag == "" {
+		return VerifyUnverified, "", nil
+	}
+
+	if _, parts, ok := splitMultipartETag(etag); ok {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", "", err
+		}
+		var actual string
+		for _, p := range candidatePartSizes(info.Size(), parts, partSize) {
+			if actual, err = fileMultipartETag(path, p); err != nil {
+				return "", "", err
+			}
+			if actual == etag {
+				return VerifyOK, actual, nil
+			}
+		}
+		// 分片大小是推断的，对不上可能只是没猜中，不能当作文件损坏
+		if actual == "" || partSize <= 0 {
+			return VerifyUnverified, actual, nil
+		}
+		return VerifyMismatch, actual, nil
+	}
+
+	actual, err := fileMD5(path)
+	if err != nil {
+		return "", "", err
+	}
+	if actual == etag {
+		return VerifyOK, actual, nil
+	}
+	return VerifyMismatch, actual, nil
+}
+
+func fileMultipartETag(path string, partSize int64) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return MultipartETag(f, partSize)
+}
+
+// quarantineFile 把校验失败的文件挪到 quarantineDir，保留 Key 的目录结构
+func quarantineFile(localPath string, quarantineDir string, key string) (string, error) {
+	target, err := SafeLocalPath(quarantineDir, key)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return "", err
+	}
+	if err := os.Rename(localPath, target); err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+// WriteVerifyReport 把校验失败的对象写成 CSV 报告
+func WriteVerifyReport(w io.Writer, results []DownloadResult) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"Key", "Path", "ETag", "Actual", "Verify"}); err != nil {
+		return fmt.Errorf("Failed to write CSV headers: %w", err)
+	}
+	for _, r := range results {
+		if r.Verify != VerifyMismatch {
+			continue
+		}
+		if err := writer.Write([]string{r.Key, r.Path, r.ETag, r.Actual, r.Verify}); err != nil {
+			return fmt.Errorf("Failed to write CSV record: %w", err)
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// SaveVerifyReport 把校验报告保存到 filePath
+func SaveVerifyReport(results []DownloadResult, filePath string) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("Failed to create report file: %w", err)
+	}
+	defer file.Close()
+	return WriteVerifyReport(file, results)
+}
@@ -0,0 +1,115 @@
+package s3viewer
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// expectedMultipartETag 按 S3 的规则手算分片 ETag
+func expectedMultipartETag(content []byte, partSize int) string {
+	var digests []byte
+	parts := 0
+	for i := 0; i < len(content); i += partSize {
+		end := min(i+partSize, len(content))
+		sum := md5.Sum(content[i:end])
+		digests = append(digests, sum[:]...)
+		parts++
+	}
+	sum := md5.Sum(digests)
+	return fmt.Sprintf("%s-%d", hex.EncodeToString(sum[:]), parts)
+}
+
+func TestIsMultipartETag(t *testing.T) {
+	assert.True(t, IsMultipartETag("d41d8cd98f00b204e9800998ecf8427e-3"))
+	assert.True(t, IsMultipartETag(`"d41d8cd98f00b204e9800998ecf8427e-12"`))
+	assert.False(t, IsMultipartETag("d41d8cd98f00b204e9800998ecf8427e"))
+	assert.False(t, IsMultipartETag("abc-3"))
+	assert.False(t, IsMultipartETag("d41d8cd98f00b204e9800998ecf8427e-x"))
+}
+
+func TestVerifyFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.bin")
+	content := []byte("0123456789")
+	assert.NoError(t, os.WriteFile(path, content, 0644))
+
+	status, actual, err := VerifyFile(path, `"`+md5Hex(content)+`"`, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, VerifyOK, status)
+	assert.Equal(t, md5Hex(content), actual)
+
+	status, _, err = VerifyFile(path, md5Hex([]byte("other")), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, VerifyMismatch, status)
+
+	// 分片大小给定
+	etag := expectedMultipartETag(content, 4)
+	status, actual, err = VerifyFile(path, etag, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, VerifyOK, status)
+	assert.Equal(t, etag, actual)
+
+	// 推断不出分片大小
+	status, _, err = VerifyFile(path, etag, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, VerifyUnverified, status)
+
+	status, _, err = VerifyFile(path, "", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, VerifyUnverified, status)
+}
+
+func TestVerifyFileInferPartSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "big.bin")
+	content := bytes.Repeat([]byte("s3viewer"), (5<<20)/8+1000)
+	assert.NoError(t, os.WriteFile(path, content, 0644))
+
+	status, _, err := VerifyFile(path, expectedMultipartETag(content, 5<<20), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, VerifyOK, status)
+
+	// 分片数一样但推断的分片大小都对不上：可能只是没猜中，不算 mismatch
+	other := expectedMultipartETag(bytes.Repeat([]byte("x"), len(content)), 5<<20)
+	status, _, err = VerifyFile(path, other, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, VerifyUnverified, status)
+
+	// 指定了分片大小还对不上才是 mismatch
+	status, _, err = VerifyFile(path, other, 5<<20)
+	assert.NoError(t, err)
+	assert.Equal(t, VerifyMismatch, status)
+}
+
+func TestDownloadVerifyQuarantine(t *testing.T) {
+	good := []byte("good content")
+	bad := []byte("corrupted content")
+	server := newObjectServer(t, map[string][]byte{"good.txt": good, "dir/bad.txt": bad})
+	files := []File{
+		{Key: "good.txt", Size: len(good), ETag: md5Hex(good), Link: server.URL + "/good.txt"},
+		{Key: "dir/bad.txt", Size: len(bad), ETag: md5Hex([]byte("original content")), Link: server.URL + "/dir/bad.txt"},
+	}
+
+	dir := t.TempDir()
+	results := (&Downloader{Dir: dir, Verify: true}).Download(files)
+	assert.Equal(t, VerifyOK, results[0].Verify)
+	assert.Equal(t, VerifyMismatch, results[1].Verify)
+	assert.Equal(t, md5Hex(bad), results[1].Actual)
+
+	// 校验失败的文件被挪进 .quarantine
+	assert.NoFileExists(t, filepath.Join(dir, "dir", "bad.txt"))
+	assert.FileExists(t, filepath.Join(dir, ".quarantine", "dir", "bad.txt"))
+	assert.Equal(t, filepath.Join(dir, ".quarantine", "dir", "bad.txt"), results[1].Path)
+
+	var report bytes.Buffer
+	assert.NoError(t, WriteVerifyReport(&report, results))
+	lines := strings.Split(strings.TrimSpace(report.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.True(t, strings.HasPrefix(lines[1], "dir/bad.txt,"))
+}
@@ -0,0 +1,209 @@
+package s3viewer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// 事件类型
+const (
+	EventCreated  = "created"
+	EventDeleted  = "deleted"
+	EventModified = "modified"
+)
+
+// Event 一次轮询发现的变化，按 JSONL 输出、POST 给 webhook
+type Event struct {
+	Type         string    `json:"type"`
+	Url          string    `json:"url"`
+	Key          string    `json:"key"`
+	Size         int       `json:"size"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified time.Time `json:"last_modified"`
+	Link         string    `json:"link,omitempty"`
+	Changes      []string  `json:"changes,omitempty"` // modified 时变化的字段
+	DetectedAt   time.Time `json:"detected_at"`
+}
+
+// Watcher 定期爬取同一个 bucket，和上一次的结果比较，产生事件
+type Watcher struct {
+	Url       string
+	MaxPage   int
+	StatePath string       // 上一次的结果保存在这里（.json 或 .csv），为空时只保存在内存里
+	Webhook   string       // 不为空时把每次轮询的事件 POST 过去
+	Client    *http.Client // 请求 webhook 用，为 nil 时使用 NewHTTPClient(30s)
+
+	state *ListBucketResult
+}
+
+// Poll 爬取一次并和上一次的结果比较；第一次（没有历史状态时）只记录状态，不产生事件。
+// 爬取失败时返回错误，状态保持不变
+func (w *Watcher) Poll() ([]Event, error) {
+	return w.PollContext(context.Background())
+}
+
+// ErrIncompleteListing 这次没有爬完整个 bucket，和上一次比较会把没爬到的对象误报为删除
+var ErrIncompleteListing = errors.New("incomplete listing")
+
+// PollContext 同 Poll；中途取消、到达 MaxPage 还有下一页、翻页失败时不比较，也不保存不完整的结果，
+// 否则这次会误报大量删除，下次又误报为新增
+func (w *Watcher) PollContext(ctx context.Context) ([]Event, error) {
+	lister := NewBackendLister(s3Backend{}, w.Url, w.MaxPage)
+	current, err := loadAll(ctx, lister)
+	if err != nil {
+		return nil, err
+	}
+	if !lister.Complete() {
+		return nil, fmt.Errorf("%w: stopped after %v pages, raise the max page or check the paging errors", ErrIncompleteListing, lister.Pages())
+	}
+	previous, err := w.loadState()
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	if previous != nil {
+		events = DiffEvents(w.Url, Diff(previous, current), time.Now())
+	}
+	if err := w.saveState(current); err != nil {
+		return events, err
+	}
+	return events, nil
+}
+
+func (w *Watcher) loadState() (*ListBucketResult, error) {
+	if w.state != nil || w.StatePath == "" {
+		return w.state, nil
+	}
+	state, err := LoadSnapshot(w.StatePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	w.state = state
+	return state, nil
+}
+
+func (w *Watcher) saveState(result *ListBucketResult) error {
+	w.state = result
+	if w.StatePath == "" {
+		return nil
+	}
+	if err := SaveResult(result, w.StatePath); err != nil {
+		return fmt.Errorf("Failed to save state: %w", err)
+	}
+	return nil
+}
+
+// DiffEvents 把 Diff 的结果转换成事件；改名拆成一个 deleted 和一个 created
+func DiffEvents(url string, d *DiffResult, now time.Time) []Event {
+	var events []Event
+	newEvent := func(typ string, f File) Event {
+		return Event{Type: typ, Url: url, Key: f.Key, Size: f.Size, ETag: f.ETag,
+			LastModified: f.LastModified, Link: f.Link, DetectedAt: now}
+	}
+	for _, f := range d.Added {
+		events = append(events, newEvent(EventCreated, f))
+	}
+	for _, r := range d.Renamed {
+		events = append(events, newEvent(EventDeleted, r.From), newEvent(EventCreated, r.To))
+	}
+	for _, f := range d.Removed {
+		events = append(events, newEvent(EventDeleted, f))
+	}
+	for _, m := range d.Modified {
+		e := newEvent(EventModified, m.New)
+		e.Changes = m.Changes
+		events = append(events, e)
+	}
+	return events
+}
+
+// WriteEvents 以 JSONL 输出事件，每行一个
+func WriteEvents(w io.Writer, events []Event) error {
+	encoder := json.NewEncoder(w)
+	for _, e := range events {
+		if err := encoder.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Notify 把一批事件以 JSON 数组 POST 给 webhook，没有事件时什么都不做
+func (w *Watcher) Notify(events []Event) error {
+	return w.NotifyContext(context.Background(), events)
+}
+
+// NotifyContext 同 Notify，ctx 取消时请求随之中断
+func (w *Watcher) NotifyContext(ctx context.Context, events []Event) error {
+	if w.Webhook == "" || len(events) == 0 {
+		return nil
+	}
+	client := w.Client
+	if client == nil {
+		client = NewHTTPClient(30 * time.Second)
+	}
+	body, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", w.Webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook: %v", resp.Status)
+	}
+	return nil
+}
+
+// Run 每隔 interval 轮询一次，事件写到 out 并通知 webhook；rounds > 0 时轮询这么多次后返回，否则一直运行。
+// 单次爬取或通知失败只打日志，下次继续
+func (w *Watcher) Run(out io.Writer, interval time.Duration, rounds int) error {
+	return w.RunContext(context.Background(), out, interval, rounds)
+}
+
+// RunContext 同 Run，ctx 取消时停止轮询并返回 nil
+func (w *Watcher) RunContext(ctx context.Context, out io.Writer, interval time.Duration, rounds int) error {
+	for round := 1; ; round++ {
+		events, err := w.PollContext(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			Logger().Warn(T("poll failed"), "url", w.Url, "err", err)
+		} else {
+			Logger().Info(T("poll done"), "round", round, "events", len(events))
+		}
+		if err := WriteEvents(out, events); err != nil {
+			return err
+		}
+		if err := w.NotifyContext(ctx, events); err != nil {
+			Logger().Warn(T("webhook failed"), "url", w.Webhook, "err", err)
+		}
+		if rounds > 0 && round >= rounds {
+			return nil
+		}
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
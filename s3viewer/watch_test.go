@@ -0,0 +1,113 @@
+package s3viewer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// bucketXML 生成只有一页的 ListBucketResult
+func bucketXML(objects map[string]string) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?><ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><Name>test</Name><IsTruncated>false</IsTruncated>`)
+	for key, etag := range objects {
+		fmt.Fprintf(&b, `<Contents><Key>%s</Key><LastModified>2024-06-01T00:00:00.000Z</LastModified><ETag>"%s"</ETag><Size>%d</Size></Contents>`, key, etag, len(etag))
+	}
+	b.WriteString(`</ListBucketResult>`)
+	return b.String()
+}
+
+func TestWatcher(t *testing.T) {
+	var mu sync.Mutex
+	objects := map[string]string{"a.txt": "aaaa", "b.txt": "bbbb"}
+	bucket := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprint(w, bucketXML(objects))
+	}))
+	defer bucket.Close()
+
+	var received [][]Event
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var events []Event
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&events))
+		received = append(received, events)
+	}))
+	defer webhook.Close()
+
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	watcher := &Watcher{Url: bucket.URL + "/", MaxPage: 1, StatePath: statePath, Webhook: webhook.URL}
+
+	// 第一次只记录状态
+	var out bytes.Buffer
+	assert.NoError(t, watcher.Run(&out, 0, 1))
+	assert.Empty(t, out.String())
+	assert.Empty(t, received)
+
+	mu.Lock()
+	objects["a.txt"] = "aaaaaa"
+	delete(objects, "b.txt")
+	objects["c.env"] = "cccc"
+	mu.Unlock()
+
+	// 重新创建 Watcher，状态从文件里读回来
+	watcher = &Watcher{Url: bucket.URL + "/", MaxPage: 1, StatePath: statePath, Webhook: webhook.URL}
+	assert.NoError(t, watcher.Run(&out, 0, 1))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 3)
+	var types []string
+	for _, line := range lines {
+		var e Event
+		assert.NoError(t, json.Unmarshal([]byte(line), &e))
+		types = append(types, e.Type+" "+e.Key)
+	}
+	assert.Equal(t, []string{"created c.env", "deleted b.txt", "modified a.txt"}, types)
+
+	assert.Len(t, received, 1)
+	assert.Len(t, received[0], 3)
+	assert.Equal(t, []string{"Size", "ETag"}, received[0][2].Changes)
+
+	// 没有变化时不产生事件，也不通知
+	out.Reset()
+	assert.NoError(t, watcher.Run(&out, 0, 1))
+	assert.Empty(t, out.String())
+	assert.Len(t, received, 1)
+}
+
+func TestWatcherPollError(t *testing.T) {
+	bucket := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "denied", http.StatusForbidden)
+	}))
+	defer bucket.Close()
+
+	watcher := &Watcher{Url: bucket.URL + "/"}
+	_, err := watcher.Poll()
+	assert.Error(t, err)
+}
+
+func TestWatcherIncompleteListing(t *testing.T) {
+	bucket := pagedServer([][]string{{"a.txt"}, {"b.txt"}})
+	defer bucket.Close()
+
+	// 只爬一页，还有下一页：不比较，也不保存状态
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	watcher := &Watcher{Url: bucket.URL + "/", MaxPage: 1, StatePath: statePath}
+	events, err := watcher.Poll()
+	assert.ErrorIs(t, err, ErrIncompleteListing)
+	assert.Empty(t, events)
+	assert.NoFileExists(t, statePath)
+
+	watcher.MaxPage = 10
+	_, err = watcher.Poll()
+	assert.NoError(t, err)
+	assert.FileExists(t, statePath)
+}
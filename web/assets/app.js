@@ -0,0 +1,131 @@
+const ICONS = {folder: "\u{1F4C1}", image: "\u{1F5BC}", video: "\u{1F3AC}", audio: "\u{1F3B5}", archive: "\u{1F4E6}",
+  document: "\u{1F4C4}", spreadsheet: "\u{1F4CA}", code: "\u{1F4DD}", text: "\u{1F4C3}", database: "\u{1F5C4}",
+  executable: "\u{2699}", certificate: "\u{1F511}", other: "\u{1F4CE}"};
+const LIMIT = 100;
+const state = {prefix: "", q: "", sort: "name", order: "asc", offset: 0};
+
+function el(tag, attrs, text) {
+  const e = document.createElement(tag);
+  for (const k in attrs || {}) e.setAttribute(k, attrs[k]);
+  if (text !== undefined) e.textContent = text;
+  return e;
+}
+
+function humanSize(n) {
+  const units = ["B", "KB", "MB", "GB", "TB"];
+  let i = 0;
+  while (n >= 1024 && i < units.length - 1) { n /= 1024; i++; }
+  return (i === 0 ? n : n.toFixed(1)) + " " + units[i];
+}
+
+function go(prefix) {
+  location.hash = encodeURIComponent(prefix);
+}
+
+function renderCrumbs(prefix) {
+  const crumbs = document.getElementById("crumbs");
+  crumbs.textContent = "";
+  const root = el("a", {}, "/");
+  root.onclick = () => go("");
+  crumbs.appendChild(root);
+  let acc = "";
+  for (const part of prefix.split("/").filter(p => p !== "")) {
+    acc += part + "/";
+    const target = acc;
+    crumbs.appendChild(el("span", {class: "sep"}, "›"));
+    const a = el("a", {}, part);
+    a.onclick = () => go(target);
+    crumbs.appendChild(a);
+  }
+}
+
+function row(icon, nameCell, type, size, date) {
+  const tr = el("tr");
+  const td = el("td", {class: "name"});
+  td.appendChild(el("span", {class: "icon"}, icon));
+  td.appendChild(nameCell);
+  tr.appendChild(td);
+  tr.appendChild(el("td", {}, type));
+  tr.appendChild(el("td", {class: "size"}, size));
+  tr.appendChild(el("td", {class: "date"}, date));
+  return tr;
+}
+
+function render(data) {
+  renderCrumbs(data.prefix);
+  const rows = document.getElementById("rows");
+  rows.textContent = "";
+  if (data.parent !== data.prefix && data.prefix !== "") {
+    const up = el("a", {}, "..");
+    up.onclick = () => go(data.parent);
+    rows.appendChild(row(ICONS.folder, up, "", "", ""));
+  }
+  if (data.offset === 0) {
+    for (const f of data.folders) {
+      const a = el("a", {}, f.name + "/");
+      a.onclick = () => go(f.prefix);
+      rows.appendChild(row(ICONS.folder, a, f.objects + " " + TEXT.objects, humanSize(f.size), ""));
+    }
+  }
+  for (const f of data.files) {
+    const name = el("span");
+    const a = el("a", f.link ? {href: f.link, target: "_blank", rel: "noreferrer"} : {}, state.q ? f.key.slice(data.prefix.length) : f.name);
+    name.appendChild(a);
+    if (f.table) {
+      name.appendChild(el("a", {class: "preview", href: "table?key=" + encodeURIComponent(f.key), target: "_blank", title: TEXT.preview}, "\u{1F441}"));
+    } else if (f.preview) {
+      name.appendChild(el("a", {class: "preview", href: "proxy?key=" + encodeURIComponent(f.key), target: "_blank", title: TEXT.preview}, "\u{1F441}"));
+    }
+    if (f.category) {
+      name.appendChild(el("span", {class: "badge " + f.severity}, f.severity));
+      name.appendChild(el("span", {class: "badge"}, f.category));
+    }
+    rows.appendChild(row(ICONS[f.type] || ICONS.other, name, f.type, humanSize(f.size), new Date(f.last_modified).toLocaleString()));
+  }
+  if (!rows.firstChild) {
+    const td = el("td", {colspan: 4, class: "empty"}, TEXT.empty);
+    const tr = el("tr");
+    tr.appendChild(td);
+    rows.appendChild(tr);
+  }
+  const end = Math.min(data.offset + data.limit, data.total);
+  document.getElementById("range").textContent = data.total ? (data.offset + 1) + "–" + end + " / " + data.total : "";
+  document.getElementById("prev").disabled = data.offset === 0;
+  document.getElementById("next").disabled = end >= data.total;
+  for (const th of document.querySelectorAll("th[data-sort]")) {
+    th.classList.toggle("sorted", th.dataset.sort === state.sort);
+    th.classList.toggle("desc", th.dataset.sort === state.sort && state.order === "desc");
+  }
+}
+
+function load() {
+  const params = new URLSearchParams({prefix: state.prefix, q: state.q, sort: state.sort, order: state.order, offset: state.offset, limit: LIMIT});
+  fetch("api/list?" + params).then(r => r.json()).then(render);
+}
+
+window.addEventListener("hashchange", () => {
+  state.prefix = decodeURIComponent(location.hash.slice(1));
+  state.offset = 0;
+  load();
+});
+let timer;
+document.getElementById("filter").addEventListener("input", e => {
+  clearTimeout(timer);
+  timer = setTimeout(() => { state.q = e.target.value; state.offset = 0; load(); }, 200);
+});
+for (const th of document.querySelectorAll("th[data-sort]")) {
+  th.onclick = () => {
+    if (state.sort === th.dataset.sort) {
+      state.order = state.order === "asc" ? "desc" : "asc";
+    } else {
+      state.sort = th.dataset.sort;
+      state.order = "asc";
+    }
+    state.offset = 0;
+    load();
+  };
+}
+document.getElementById("prev").onclick = () => { state.offset = Math.max(0, state.offset - LIMIT); load(); };
+document.getElementById("next").onclick = () => { state.offset += LIMIT; load(); };
+state.prefix = decodeURIComponent(location.hash.slice(1));
+load();
@@ -1,143 +1,309 @@
 package web
 
 import (
-	"fmt"
+	"embed"
+	"encoding/json"
+	"github.com/hi-unc1e/s3viewer-go/s3viewer"
 	"html/template"
-	"log"
 	"net"
 	"net/http"
 	"os"
-	"path/filepath"
+	"path"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
-// Image 结构体用于存储图片信息
-type Image struct {
-	Name string
-	URL  string
-}
+// DefaultListen -listen 的默认地址
+const DefaultListen = "127.0.0.1:30028"
+
+// Server 在浏览器里浏览爬取结果：按前缀分目录、排序、过滤、分页，数据来自内存里的 ListBucketResult
+type Server struct {
+	Client         *http.Client // 预览代理和表格预览用，为 nil 时使用 NewHTTPClient(1min)
+	MaxPreviewSize int64        // 预览代理最多转发的字节数，为 0 时使用 DefaultMaxPreviewSize
+
+	result *s3viewer.ListBucketResult
+	files  []s3viewer.File // 按 Key 排序
+	byKey  map[string]s3viewer.File
+	mux    *http.ServeMux
 
-// ImagePage 结构体用于存储页面上的所有图片信息
-type ImagePage struct {
-	Title  string
-	Images []Image
+	clientOnce sync.Once
 }
 
-// generateImagePage 函数生成HTML页面并保存到本地
-func generateImagePage(imageURLs []string, outputPath string) error {
-	// 创建一个ImagePage实例，包含所有图片信息
-	page := ImagePage{
-		Title:  "图片展示",
-		Images: make([]Image, len(imageURLs)),
+// NewServer 使用自己的 ServeMux，不注册到 http.DefaultServeMux
+func NewServer(result *s3viewer.ListBucketResult) *Server {
+	files := append([]s3viewer.File(nil), result.Files...)
+	sort.Slice(files, func(i, j int) bool { return files[i].Key < files[j].Key })
+
+	byKey := make(map[string]s3viewer.File, len(files))
+	for _, file := range files {
+		byKey[file.Key] = file
 	}
 
-	// 填充图片信息
-	for i, url := range imageURLs {
-		// 从URL中提取文件名
-		name := strings.Split(url, "/")[len(strings.Split(url, "/"))-1]
-		page.Images[i] = Image{Name: name, URL: url}
-	}
-
-	// 定义HTML模板
-	tmpl, err := template.New("imagePage").Parse(`
-<!DOCTYPE html>
-<html lang="zh-CN">
-<head>
-<meta charset="UTF-8">
-<meta name="viewport" content="width=device-width, initial-scale=1.0">
-<title>{{.Title}}</title>
-<link rel="stylesheet" href="https://cdn.staticfile.org/twitter-bootstrap/4.5.0/css/bootstrap.min.css">
-</head>
-<body>
-<div class="container">
-<div class="row">
-{{range .Images}}
-<div class="col-md-4">
-	<div class="card mb-4 shadow-sm">
-		<img src="{{.URL}}" class="card-img-top" alt="{{.Name}}">
-		<div class="card-body">
-			<h5 class="card-title">{{.Name}}</h5>
-			<p class="card-text"><a href="{{.URL}}" download="{{.Name}}" class="btn btn-primary">下载图片</a></p>
-		</div>
-	</div>
-</div>
-{{end}}
-</div>
-</div>
-<script src="https://cdn.staticfile.org/jquery/3.5.1/jquery.min.js"></script>
-<script src="https://cdn.staticfile.org/popper.js/1.16.0/umd/popper.min.js"></script>
-<script src="https://cdn.staticfile.org/twitter-bootstrap/4.5.0/js/bootstrap.min.js"></script>
-</body>
-</html>
-`)
+	s := &Server{result: result, files: files, byKey: byKey, mux: http.NewServeMux()}
+	s.mux.HandleFunc("/", s.handleIndex)
+	s.mux.HandleFunc("/api/list", s.handleList)
+	s.mux.HandleFunc("/proxy", s.handleProxy)
+	s.mux.HandleFunc("/table", s.handleTable)
+	s.mux.Handle("/assets/", http.FileServer(http.FS(assets)))
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// ListenAndServe 在 addr 上提供页面，端口为 0 时随机选择一个端口
+func (s *Server) ListenAndServe(addr string) error {
+	listener, err := net.Listen("tcp", addr)
 	if err != nil {
 		return err
 	}
+	s3viewer.Logger().Info(s3viewer.T("Server started, open http://%v/ to browse the bucket", listener.Addr()))
+	return http.Serve(listener, s)
+}
 
-	// 确保输出目录存在
-	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
-		return err
+// ListenAndServe 使用默认设置浏览 result
+func ListenAndServe(addr string, result *s3viewer.ListBucketResult) error {
+	return NewServer(result).ListenAndServe(addr)
+}
+
+// ServeHttp 在随机端口上浏览 files
+func ServeHttp(files []s3viewer.File) {
+	if err := ListenAndServe("127.0.0.1:0", &s3viewer.ListBucketResult{Files: files}); err != nil {
+		s3viewer.Logger().Error(s3viewer.T("Failed to start the server: %v", err))
+		os.Exit(1)
 	}
+}
 
-	// 创建HTML文件
-	file, err := os.Create(outputPath)
-	if err != nil {
-		return err
+// 页面和静态文件都编译进程序，不依赖 CDN，也不往磁盘写文件
+var (
+	//go:embed templates
+	templates embed.FS
+	//go:embed assets
+	assets embed.FS
+
+	indexTemplate = template.Must(template.ParseFS(templates, "templates/index.html"))
+)
+
+// IndexPage 首页模板的数据，页面内容由 /api/list 填充
+type IndexPage struct {
+	Lang  string // <html lang>
+	Title string
+	Url   string
+	Total int
+	Text  map[string]string // 页面脚本里用到的文字
+}
+
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
 	}
-	defer file.Close()
+	page := IndexPage{
+		Lang:  "en",
+		Title: s3viewer.T("Bucket browser"),
+		Url:   s.result.Url,
+		Total: len(s.files),
+		Text: map[string]string{
+			"filter":   s3viewer.T("Filter by name"),
+			"name":     s3viewer.T("Name"),
+			"size":     s3viewer.T("Size"),
+			"modified": s3viewer.T("Last modified"),
+			"type":     s3viewer.T("Type"),
+			"prev":     s3viewer.T("Previous"),
+			"next":     s3viewer.T("Next"),
+			"empty":    s3viewer.T("No objects"),
+			"objects":  s3viewer.T("objects"),
+			"preview":  s3viewer.T("Preview"),
+		},
+	}
+	if s3viewer.Lang() == s3viewer.LangZH {
+		page.Lang = "zh-CN"
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := indexTemplate.Execute(w, page); err != nil {
+		s3viewer.Logger().Error(s3viewer.T("Failed to generate the page: %v", err))
+	}
+}
 
-	// 使用模板生成HTML页面并写入文件
-	return tmpl.Execute(file, page)
+// Folder /api/list 返回的子目录
+type Folder struct {
+	Prefix  string `json:"prefix"`
+	Name    string `json:"name"`
+	Objects int    `json:"objects"` // 目录下（包括子目录）的对象数
+	Size    int64  `json:"size"`
 }
 
-// staticHandler 用于提供静态文件服务
-func staticHandler(w http.ResponseWriter, r *http.Request) {
-	// 设置静态文件服务目录为./static
-	staticDir := "./static"
-	// 获取请求的文件路径
-	filePath := r.URL.Path[len("/static"):]
+// Entry /api/list 返回的对象
+type Entry struct {
+	Key          string    `json:"key"`
+	Name         string    `json:"name"`
+	Size         int       `json:"size"`
+	LastModified time.Time `json:"last_modified"`
+	ETag         string    `json:"etag,omitempty"`
+	Link         string    `json:"link,omitempty"`
+	Type         string    `json:"type"` // 见 FileType
+	Category     string    `json:"category,omitempty"`
+	Severity     string    `json:"severity,omitempty"`
+	Preview      bool      `json:"preview"` // 可以通过 /proxy 预览
+	Table        bool      `json:"table"`   // 可以通过 /table 按表格预览
+}
 
-	// 读取本地文件并返回
-	http.ServeFile(w, r, filepath.Join(staticDir, filePath))
+// Listing /api/list 的响应
+type Listing struct {
+	Prefix  string   `json:"prefix"`
+	Parent  string   `json:"parent"`
+	Folders []Folder `json:"folders"`
+	Files   []Entry  `json:"files"`
+	Total   int      `json:"total"` // 过滤后、分页前的对象数
+	Offset  int      `json:"offset"`
+	Limit   int      `json:"limit"`
 }
 
-// 获取可用端口
-func GetAvailablePort() (int, error) {
-	address, err := net.ResolveTCPAddr("tcp", fmt.Sprintf("%s:0", "0.0.0.0"))
-	if err != nil {
-		return 0, err
+// handleList GET /api/list?prefix=a/&q=.sql&sort=name|size|date|type&order=asc|desc&offset=0&limit=100
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	offset, _ := strconv.Atoi(query.Get("offset"))
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	listing := s.List(query.Get("prefix"), query.Get("q"), query.Get("sort"), query.Get("order") == "desc", offset, limit)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(listing)
+}
+
+// List 列出 prefix 下一层的目录和对象；q 不为空时不再分目录，
+// 列出 prefix 下所有名字包含 q 的对象（大小写不敏感）
+func (s *Server) List(prefix, q, sortBy string, desc bool, offset, limit int) *Listing {
+	if limit <= 0 || limit > 1000 {
+		limit = 100
 	}
+	if offset < 0 {
+		offset = 0
+	}
+	listing := &Listing{Prefix: prefix, Parent: parentPrefix(prefix), Folders: []Folder{}, Files: []Entry{}, Offset: offset, Limit: limit}
+	q = strings.ToLower(q)
 
-	listener, err := net.ListenTCP("tcp", address)
-	if err != nil {
-		return 0, err
+	// files 按 Key 排序，从第一个 >= prefix 的开始
+	start := sort.Search(len(s.files), func(i int) bool { return s.files[i].Key >= prefix })
+	var files []s3viewer.File
+	folders := make(map[string]int) // 目录名 -> listing.Folders 下标
+	for _, file := range s.files[start:] {
+		if !strings.HasPrefix(file.Key, prefix) {
+			break
+		}
+		rest := file.Key[len(prefix):]
+		if rest == "" {
+			continue
+		}
+		if q != "" {
+			if strings.Contains(strings.ToLower(rest), q) {
+				files = append(files, file)
+			}
+			continue
+		}
+		if i := strings.Index(rest, "/"); i >= 0 {
+			name := rest[:i]
+			idx, ok := folders[name]
+			if !ok {
+				idx = len(listing.Folders)
+				folders[name] = idx
+				listing.Folders = append(listing.Folders, Folder{Prefix: prefix + name + "/", Name: name})
+			}
+			listing.Folders[idx].Objects++
+			listing.Folders[idx].Size += int64(file.Size)
+			continue
+		}
+		files = append(files, file)
 	}
 
-	defer listener.Close()
-	return listener.Addr().(*net.TCPAddr).Port, nil
+	sortFiles(files, sortBy, desc)
+	listing.Total = len(files)
+	if offset < len(files) {
+		files = files[offset:]
+		if len(files) > limit {
+			files = files[:limit]
+		}
+		for _, file := range files {
+			listing.Files = append(listing.Files, Entry{
+				Key:          file.Key,
+				Name:         path.Base(file.Key),
+				Size:         file.Size,
+				LastModified: file.LastModified,
+				ETag:         file.ETag,
+				Link:         file.Link,
+				Type:         FileType(file.Key),
+				Category:     file.Category,
+				Severity:     file.Severity,
+				Preview:      file.Link != "" && file.Archive == "" && Previewable(file.Key),
+				Table:        file.Link != "" && file.Archive == "" && s3viewer.IsTable(file.Key),
+			})
+		}
+	}
+	return listing
+}
 
+// sortFiles 按 name（默认）、size、date 或 type 排序
+func sortFiles(files []s3viewer.File, by string, desc bool) {
+	less := func(a, b s3viewer.File) bool { return a.Key < b.Key }
+	switch by {
+	case "size":
+		less = func(a, b s3viewer.File) bool { return a.Size < b.Size }
+	case "date":
+		less = func(a, b s3viewer.File) bool { return a.LastModified.Before(b.LastModified) }
+	case "type":
+		less = func(a, b s3viewer.File) bool { return FileType(a.Key) < FileType(b.Key) }
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		if desc {
+			return less(files[j], files[i])
+		}
+		return less(files[i], files[j])
+	})
 }
 
-func ServeHttp(imageURLs []string) {
-	// 生成HTML页面并保存在./static/index.html
-	if err := generateImagePage(imageURLs, "./static/index.html"); err != nil {
-		fmt.Println("生成页面出错:", err)
-		return
+// parentPrefix a/b/ 的上一级是 a/，a/ 的上一级是 ""
+func parentPrefix(prefix string) string {
+	trimmed := strings.TrimSuffix(prefix, "/")
+	if i := strings.LastIndex(trimmed, "/"); i >= 0 {
+		return trimmed[:i+1]
 	}
+	return ""
+}
 
-	// 设置HTTP服务器的路由
-	http.HandleFunc("/static/", staticHandler)
+var fileTypes = map[string][]string{
+	"image":       {"jpg", "jpeg", "png", "gif", "bmp", "webp", "svg", "ico", "tif", "tiff", "heic"},
+	"video":       {"mp4", "mov", "avi", "mkv", "flv", "wmv", "webm", "m3u8"},
+	"audio":       {"mp3", "wav", "flac", "aac", "ogg", "m4a", "amr"},
+	"archive":     {"zip", "rar", "7z", "tar", "gz", "tgz", "bz2", "xz", "war", "jar"},
+	"document":    {"pdf", "doc", "docx", "ppt", "pptx", "odt", "rtf", "md"},
+	"spreadsheet": {"xls", "xlsx", "csv", "tsv", "ods"},
+	"code":        {"js", "go", "py", "java", "php", "c", "cpp", "h", "rb", "sh", "html", "htm", "css", "json", "xml", "yml", "yaml", "ini", "conf", "env", "properties"},
+	"text":        {"txt", "log"},
+	"database":    {"sql", "db", "sqlite", "mdb", "bak", "dump"},
+	"executable":  {"apk", "ipa", "exe", "dll", "so", "dmg", "msi", "deb", "rpm", "bin"},
+	"certificate": {"pem", "key", "crt", "cer", "p12", "pfx", "jks"},
+}
 
-	// 启动HTTP服务器
-	// 创建一个TCP Listener，自动选择端口号
-	port, err := GetAvailablePort() // :0 表示随机选择一个端口号
-	if err != nil {
-		log.Fatal("监听端口失败:", err)
+var fileTypeByExt = func() map[string]string {
+	m := make(map[string]string)
+	for typ, exts := range fileTypes {
+		for _, ext := range exts {
+			m[ext] = typ
+		}
 	}
-	fmt.Printf("服务器正在启动，访问 http://localhost:%v/static/index.html 查看图片展示页面\n", port)
-	if err := http.ListenAndServe(fmt.Sprintf("127.0.0.1:%v", port), nil); err != nil {
-		log.Fatal("服务器启动失败:", err)
+	return m
+}()
+
+// FileType 根据扩展名得到的大类（image、video、archive、spreadsheet……），页面据此选图标，识别不出时为 other
+func FileType(key string) string {
+	ext := strings.ToLower(strings.TrimPrefix(path.Ext(key), "."))
+	if typ, ok := fileTypeByExt[ext]; ok {
+		return typ
 	}
+	return "other"
 }
 
 func main() {
@@ -154,5 +320,9 @@ func main() {
 		// 添加更多图片URL
 	}
 
-	ServeHttp(imageURLs)
+	files := make([]s3viewer.File, len(imageURLs))
+	for i, url := range imageURLs {
+		files[i] = s3viewer.File{Link: url}
+	}
+	ServeHttp(files)
 }
@@ -0,0 +1,117 @@
+package web
+
+import (
+	"encoding/json"
+	"github.com/hi-unc1e/s3viewer-go/s3viewer"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func testResult() *s3viewer.ListBucketResult {
+	day := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	return &s3viewer.ListBucketResult{Url: "http://bucket.example.com/", Files: []s3viewer.File{
+		{Key: "docs/e.sql", Size: 30, LastModified: day.AddDate(0, 0, 2)},
+		{Key: "a.txt", Size: 5, LastModified: day},
+		{Key: "docs/b.pdf", Size: 10, LastModified: day.AddDate(0, 0, 1)},
+		{Key: "docs/c/d.PNG", Size: 20, LastModified: day},
+		{Key: "docs/", Size: 0, LastModified: day},
+	}}
+}
+
+func entryKeys(entries []Entry) []string {
+	var keys []string
+	for _, e := range entries {
+		keys = append(keys, e.Key)
+	}
+	return keys
+}
+
+func TestServerList(t *testing.T) {
+	s := NewServer(testResult())
+
+	// 根目录：docs/ 下的对象合并成一个目录
+	listing := s.List("", "", "", false, 0, 0)
+	assert.Equal(t, []Folder{{Prefix: "docs/", Name: "docs", Objects: 4, Size: 60}}, listing.Folders)
+	assert.Equal(t, []string{"a.txt"}, entryKeys(listing.Files))
+	assert.Equal(t, 1, listing.Total)
+	assert.Equal(t, 100, listing.Limit)
+
+	// 子目录，目录占位对象本身不列出
+	listing = s.List("docs/", "", "", false, 0, 0)
+	assert.Equal(t, "", listing.Parent)
+	assert.Equal(t, []Folder{{Prefix: "docs/c/", Name: "c", Objects: 1, Size: 20}}, listing.Folders)
+	assert.Equal(t, []string{"docs/b.pdf", "docs/e.sql"}, entryKeys(listing.Files))
+	assert.Equal(t, "document", listing.Files[0].Type)
+	assert.Equal(t, "docs/", s.List("docs/c/", "", "", false, 0, 0).Parent)
+
+	// 排序
+	assert.Equal(t, []string{"docs/e.sql", "docs/b.pdf"}, entryKeys(s.List("docs/", "", "size", true, 0, 0).Files))
+	assert.Equal(t, []string{"docs/b.pdf", "docs/e.sql"}, entryKeys(s.List("docs/", "", "date", false, 0, 0).Files))
+	assert.Equal(t, []string{"docs/e.sql", "docs/b.pdf"}, entryKeys(s.List("docs/", "", "date", true, 0, 0).Files))
+
+	// 分页：limit 超出范围时用默认值，offset 为负时从 0 开始，超出总数时为空
+	listing = s.List("docs/", "", "", false, 1, 1)
+	assert.Equal(t, []string{"docs/e.sql"}, entryKeys(listing.Files))
+	assert.Equal(t, 2, listing.Total)
+	listing = s.List("docs/", "", "", false, -5, 5000)
+	assert.Equal(t, 0, listing.Offset)
+	assert.Equal(t, 100, listing.Limit)
+	assert.Len(t, listing.Files, 2)
+	assert.Empty(t, s.List("docs/", "", "", false, 10, 1).Files)
+
+	// 过滤时不分目录，大小写不敏感
+	listing = s.List("", "png", "", false, 0, 0)
+	assert.Empty(t, listing.Folders)
+	assert.Equal(t, []string{"docs/c/d.PNG"}, entryKeys(listing.Files))
+	assert.Equal(t, "image", listing.Files[0].Type)
+	assert.Empty(t, s.List("docs/c/", "sql", "", false, 0, 0).Files)
+}
+
+func TestServerAPIList(t *testing.T) {
+	server := httptest.NewServer(NewServer(testResult()))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/list?prefix=docs/&sort=size&order=desc&limit=1")
+	if err != nil {
+		t.Fatalf("Failed to request /api/list: %v", err)
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	var listing Listing
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&listing))
+	assert.Equal(t, "docs/", listing.Prefix)
+	assert.Equal(t, []string{"docs/e.sql"}, entryKeys(listing.Files))
+	assert.Equal(t, 2, listing.Total)
+	assert.Len(t, listing.Folders, 1)
+
+	resp, err = http.Get(server.URL + "/")
+	if err != nil {
+		t.Fatalf("Failed to request /: %v", err)
+	}
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Get(server.URL + "/assets/app.js")
+	if err != nil {
+		t.Fatalf("Failed to request /assets/app.js: %v", err)
+	}
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Get(server.URL + "/nope")
+	if err != nil {
+		t.Fatalf("Failed to request /nope: %v", err)
+	}
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestFileType(t *testing.T) {
+	assert.Equal(t, "image", FileType("a/B.JPG"))
+	assert.Equal(t, "archive", FileType("backup.tar.gz"))
+	assert.Equal(t, "executable", FileType("app.apk"))
+	assert.Equal(t, "other", FileType("README"))
+}
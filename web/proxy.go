@@ -0,0 +1,173 @@
+package web
+
+import (
+	"fmt"
+	"github.com/hi-unc1e/s3viewer-go/s3viewer"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// DefaultMaxPreviewSize 预览代理默认最多转发的字节数
+const DefaultMaxPreviewSize = 20 << 20
+
+// previewCSP 代理返回的内容一律放进没有脚本权限的沙箱，bucket 里的 HTML/SVG 不能在本页面的源下执行
+const previewCSP = "sandbox; default-src 'none'; img-src data:; media-src data:; style-src 'unsafe-inline'"
+
+// previewTypes 允许预览的 Content-Type；值为 true 的按 text/plain 返回
+var previewTypes = map[string]bool{
+	"image/png":                false,
+	"image/jpeg":               false,
+	"image/gif":                false,
+	"image/webp":               false,
+	"image/bmp":                false,
+	"image/x-icon":             false,
+	"image/vnd.microsoft.icon": false,
+	"video/mp4":                false,
+	"video/webm":               false,
+	"audio/mpeg":               false,
+	"audio/wav":                false,
+	"audio/ogg":                false,
+	"application/pdf":          false,
+	"text/plain":               true,
+	"text/csv":                 true,
+	"application/json":         true,
+	"application/xml":          true,
+	"text/xml":                 true,
+	"text/html":                true, // 主动内容，默认当文本看，render=1 时在沙箱里渲染
+	"image/svg+xml":            true,
+}
+
+// activeTypes 可以带脚本的类型
+var activeTypes = map[string]bool{"text/html": true, "image/svg+xml": true}
+
+// Previewable 判断页面上是否给 key 显示预览链接，最终以代理拿到的 Content-Type 为准
+func Previewable(key string) bool {
+	switch FileType(key) {
+	case "image", "video", "audio", "text", "code":
+		return true
+	case "document", "spreadsheet":
+		ext := strings.ToLower(path.Ext(key))
+		return ext == ".pdf" || ext == ".csv" || ext == ".md"
+	}
+	return false
+}
+
+// client 所有预览请求共用一个客户端，每次新建的话 Transport 里的空闲连接会一直留着
+func (s *Server) client() *http.Client {
+	s.clientOnce.Do(func() {
+		if s.Client == nil {
+			s.Client = s3viewer.NewHTTPClient(time.Minute)
+		}
+	})
+	return s.Client
+}
+
+func (s *Server) maxPreviewSize() int64 {
+	if s.MaxPreviewSize > 0 {
+		return s.MaxPreviewSize
+	}
+	return DefaultMaxPreviewSize
+}
+
+// handleProxy GET /proxy?key=a/b.png[&render=1]：用爬虫的 HTTP 客户端取回对象再转给浏览器，
+// 避开混合内容、CORS、Referer 校验和自签名证书的问题。只代理列表里有的 key，
+// 超过大小上限或不在 previewTypes 里的内容拒绝
+func (s *Server) handleProxy(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+	file, ok := s.byKey[key]
+	if !ok {
+		http.Error(w, s3viewer.T("Object not in the listing"), http.StatusNotFound)
+		return
+	}
+	if file.Link == "" || file.Archive != "" {
+		http.Error(w, s3viewer.T("Object has no download link"), http.StatusNotFound)
+		return
+	}
+	limit := s.maxPreviewSize()
+	if int64(file.Size) > limit {
+		http.Error(w, s3viewer.T("Object is larger than the preview limit %v bytes", limit), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	req, err := s3viewer.NewRequestContext(r.Context(), "GET", file.Link)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	resp, err := s.client().Do(req)
+	if err != nil {
+		http.Error(w, s3viewer.T("Failed to fetch remote URL: %v", err), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		http.Error(w, s3viewer.T("Unexpected status: %v", resp.Status), http.StatusBadGateway)
+		return
+	}
+	if resp.ContentLength > limit {
+		http.Error(w, s3viewer.T("Object is larger than the preview limit %v bytes", limit), http.StatusRequestEntityTooLarge)
+		return
+	}
+	// Content-Length 可能没有或者不可信，先读到内存里再判断
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		http.Error(w, s3viewer.T("Failed to fetch remote URL: %v", err), http.StatusBadGateway)
+		return
+	}
+	if int64(len(data)) > limit {
+		http.Error(w, s3viewer.T("Object is larger than the preview limit %v bytes", limit), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	contentType := previewContentType(resp.Header.Get("Content-Type"), key, data)
+	asText, ok := previewMode(contentType)
+	if !ok {
+		http.Error(w, s3viewer.T("Content type %v can not be previewed", contentType), http.StatusUnsupportedMediaType)
+		return
+	}
+	if activeTypes[contentType] && r.URL.Query().Get("render") == "1" {
+		asText = false
+	}
+	if asText {
+		contentType = "text/plain; charset=utf-8"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Security-Policy", previewCSP)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": path.Base(key)}))
+	w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+	w.Write(data)
+}
+
+// previewMode 判断 contentType 能否预览、是否按文本返回；previewTypes 之外的 text/* 和脚本源码也按文本返回
+func previewMode(contentType string) (asText bool, ok bool) {
+	if asText, ok := previewTypes[contentType]; ok {
+		return asText, true
+	}
+	if strings.HasPrefix(contentType, "text/") || strings.HasSuffix(contentType, "javascript") || strings.HasSuffix(contentType, "+json") || strings.HasSuffix(contentType, "+xml") {
+		return true, true
+	}
+	return false, false
+}
+
+// previewContentType 取服务器声明的类型；没有声明或者是 octet-stream 时按扩展名、再按内容判断
+func previewContentType(declared, key string, data []byte) string {
+	mediaType, _, _ := mime.ParseMediaType(declared)
+	if mediaType == "" || mediaType == "application/octet-stream" || mediaType == "binary/octet-stream" {
+		mediaType, _, _ = mime.ParseMediaType(mime.TypeByExtension(strings.ToLower(path.Ext(key))))
+	}
+	if mediaType == "" {
+		mediaType, _, _ = mime.ParseMediaType(http.DetectContentType(data))
+	}
+	// 按内容识别出的主动内容不能因为声明成别的类型就绕过
+	if sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(data)); sniffed == "text/html" {
+		return sniffed
+	}
+	return strings.ToLower(mediaType)
+}
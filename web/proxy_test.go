@@ -0,0 +1,106 @@
+package web
+
+import (
+	"github.com/hi-unc1e/s3viewer-go/s3viewer"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// proxyFixture bucket 里的对象和声明的 Content-Type，返回列出这些对象的 Server 和它的地址
+func proxyFixture(t *testing.T, objects map[string][2]string) (*Server, string) {
+	t.Helper()
+	bucket := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		object, ok := objects[strings.TrimPrefix(r.URL.Path, "/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", object[1])
+		io.WriteString(w, object[0])
+	}))
+	t.Cleanup(bucket.Close)
+
+	result := &s3viewer.ListBucketResult{}
+	for key := range objects {
+		// 列表里的 Size 可能不准，代理要按实际读到的大小判断
+		result.Files = append(result.Files, s3viewer.File{Key: key, Size: 1, Link: bucket.URL + "/" + key})
+	}
+	s := NewServer(result)
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+	return s, server.URL
+}
+
+func get(t *testing.T, url string) (*http.Response, string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("Failed to request %v: %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp, string(body)
+}
+
+func TestProxy(t *testing.T) {
+	html := `<html><script>alert(1)</script></html>`
+	svg := `<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`
+	s, url := proxyFixture(t, map[string][2]string{
+		"a.png":    {"\x89PNG\r\n\x1a\n....", "image/png"},
+		"x.html":   {html, "text/html"},
+		"s.svg":    {svg, "image/svg+xml"},
+		"fake.png": {html, "image/png"},
+		"main.go":  {"package main", ""},
+		"app.exe":  {"MZ\x90\x00", "application/x-msdownload"},
+		"big.txt":  {strings.Repeat("x", 2048), "text/plain"},
+	})
+	s.MaxPreviewSize = 1024
+
+	resp, body := get(t, url+"/proxy?key=a.png")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "image/png", resp.Header.Get("Content-Type"))
+	assert.Equal(t, previewCSP, resp.Header.Get("Content-Security-Policy"))
+	assert.Equal(t, "nosniff", resp.Header.Get("X-Content-Type-Options"))
+	assert.Equal(t, "no-referrer", resp.Header.Get("Referrer-Policy"))
+	assert.True(t, strings.HasPrefix(body, "\x89PNG"))
+
+	// 主动内容按文本返回；声明成图片的 HTML 也一样
+	for _, key := range []string{"x.html", "s.svg", "fake.png", "main.go"} {
+		resp, _ = get(t, url+"/proxy?key="+key)
+		assert.Equal(t, http.StatusOK, resp.StatusCode, key)
+		assert.Equal(t, "text/plain; charset=utf-8", resp.Header.Get("Content-Type"), key)
+		assert.Equal(t, previewCSP, resp.Header.Get("Content-Security-Policy"), key)
+		assert.Equal(t, "nosniff", resp.Header.Get("X-Content-Type-Options"), key)
+	}
+
+	// render=1 时按原类型返回，但在禁止脚本的沙箱里
+	resp, body = get(t, url+"/proxy?key=x.html&render=1")
+	assert.Equal(t, "text/html", resp.Header.Get("Content-Type"))
+	assert.True(t, strings.HasPrefix(resp.Header.Get("Content-Security-Policy"), "sandbox;"))
+	assert.Equal(t, html, body)
+
+	// 不在白名单里
+	resp, _ = get(t, url+"/proxy?key=app.exe")
+	assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+
+	// 超过大小上限：列表里的 Size 不可信，按实际读到的判断
+	resp, _ = get(t, url+"/proxy?key=big.txt")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+
+	// 列表里没有的 key
+	resp, _ = get(t, url+"/proxy?key=../../etc/passwd")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestProxySizeFromListing(t *testing.T) {
+	s, url := proxyFixture(t, map[string][2]string{"a.txt": {"hello", "text/plain"}})
+	s.byKey["a.txt"] = s3viewer.File{Key: "a.txt", Size: 1 << 30, Link: s.byKey["a.txt"].Link}
+
+	// 列表里已经超过上限的不请求
+	resp, _ := get(t, url+"/proxy?key=a.txt")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+}
@@ -0,0 +1,93 @@
+package web
+
+import (
+	"github.com/hi-unc1e/s3viewer-go/s3viewer"
+	"html/template"
+	"net/http"
+	"strconv"
+)
+
+var tableTemplate = template.Must(template.New("table.html").Funcs(template.FuncMap{
+	"inc":     func(i int) int { return i + 1 },
+	"columns": columnNames,
+	"pad": func(row []string, n int) []string {
+		for len(row) < n {
+			row = append(row, "")
+		}
+		return row
+	},
+}).ParseFS(templates, "templates/table.html"))
+
+// columnNames 表头 A、B……Z、AA、AB……
+func columnNames(n int) []string {
+	names := make([]string, n)
+	for i := range names {
+		name := ""
+		for c := i + 1; c > 0; c = (c - 1) / 26 {
+			name = string(rune('A'+(c-1)%26)) + name
+		}
+		names[i] = name
+	}
+	return names
+}
+
+// TablePage 表格预览页面的数据
+type TablePage struct {
+	Lang    string
+	Key     string
+	Table   *s3viewer.Table
+	Columns int // 最宽一行的列数，短的行补空单元格
+	Text    map[string]string
+}
+
+// handleTable GET /table?key=a/users.xlsx&sheet=Sheet1&rows=100：用 Range 读取表格的前几行，渲染成 HTML 表格
+func (s *Server) handleTable(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	key := query.Get("key")
+	file, ok := s.byKey[key]
+	if !ok {
+		http.Error(w, s3viewer.T("Object not in the listing"), http.StatusNotFound)
+		return
+	}
+	if file.Link == "" || file.Archive != "" {
+		http.Error(w, s3viewer.T("Object has no download link"), http.StatusNotFound)
+		return
+	}
+	if !s3viewer.IsTable(key) {
+		http.Error(w, s3viewer.T("Only csv, tsv and xlsx can be previewed as a table"), http.StatusUnsupportedMediaType)
+		return
+	}
+	rows, _ := strconv.Atoi(query.Get("rows"))
+	if rows <= 0 || rows > 1000 {
+		rows = 100
+	}
+
+	previewer := &s3viewer.TablePreviewer{Client: s.client(), MaxRows: rows}
+	table, err := previewer.Preview(r.Context(), file, query.Get("sheet"))
+	if err != nil {
+		http.Error(w, s3viewer.T("Failed to preview the table: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	page := TablePage{
+		Lang:  "en",
+		Key:   key,
+		Table: table,
+		Text: map[string]string{
+			"encoding":  s3viewer.T("Encoding"),
+			"truncated": s3viewer.T("Showing the first %v rows", len(table.Rows)),
+		},
+	}
+	if s3viewer.Lang() == s3viewer.LangZH {
+		page.Lang = "zh-CN"
+	}
+	for _, row := range table.Rows {
+		page.Columns = max(page.Columns, len(row))
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	// 表格内容来自不可信的 bucket，页面不需要脚本
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'self'")
+	if err := tableTemplate.Execute(w, page); err != nil {
+		s3viewer.Logger().Error(s3viewer.T("Failed to generate the page: %v", err))
+	}
+}
@@ -0,0 +1,32 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestTable(t *testing.T) {
+	_, url := proxyFixture(t, map[string][2]string{
+		"users.csv": {"name,phone\n<b>bob</b>,13800000000\n", "text/csv"},
+		"a.png":     {"\x89PNG", "image/png"},
+	})
+
+	resp, body := get(t, url+"/table?key=users.csv")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/html; charset=utf-8", resp.Header.Get("Content-Type"))
+	assert.Contains(t, resp.Header.Get("Content-Security-Policy"), "default-src 'none'")
+	assert.Contains(t, body, "<td>13800000000</td>")
+	// 单元格内容转义
+	assert.Contains(t, body, "<td>&lt;b&gt;bob&lt;/b&gt;</td>")
+	assert.False(t, strings.Contains(body, "<b>bob</b>"))
+
+	// 列表里没有的 key
+	resp, _ = get(t, url+"/table?key=missing.csv")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// 不是表格
+	resp, _ = get(t, url+"/table?key=a.png")
+	assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+}